@@ -0,0 +1,70 @@
+// Package bibliography loads BibTeX and CSL-JSON files, and resolves keys of
+// citation commands declared in the schema.
+package bibliography
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+var ErrInvalidBibliography = errors.New("invalid bibliography")
+var ErrDuplicateKey = errors.New("duplicate key")
+var ErrUnknownFormat = errors.New("unknown bibliography format")
+
+// Entry is a bibliography entry. Field names are lowercase. Names of people
+// are joined with " and ", as in BibTeX.
+type Entry struct {
+	Key    string
+	Type   string
+	Fields map[string]string
+}
+
+// Bibliography holds entries in the order they are defined
+type Bibliography struct {
+	entries map[string]*Entry
+	keys    []string
+}
+
+func newBibliography() *Bibliography {
+	return &Bibliography{
+		entries: map[string]*Entry{},
+		keys:    []string{},
+	}
+}
+
+func (b *Bibliography) add(entry *Entry) error {
+	if _, ok := b.entries[entry.Key]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateKey, entry.Key)
+	}
+
+	b.entries[entry.Key] = entry
+	b.keys = append(b.keys, entry.Key)
+
+	return nil
+}
+
+// Load parses the file as BibTeX or CSL-JSON, depending on the extension of
+// its name: `.bib` or `.json`
+func Load(name string, data []byte) (*Bibliography, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".bib":
+		return ParseBibTeX(data)
+	case ".json":
+		return ParseCSLJSON(data)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, name)
+	}
+}
+
+// Get returns the entry with the key
+func (b *Bibliography) Get(key string) (*Entry, bool) {
+	entry, ok := b.entries[key]
+	return entry, ok
+}
+
+// Keys returns keys of all entries, in the order they are defined
+func (b *Bibliography) Keys() []string {
+	return b.keys
+}
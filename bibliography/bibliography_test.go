@@ -0,0 +1,247 @@
+package bibliography_test
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ubavic/mint/bibliography"
+	"github.com/ubavic/mint/parser"
+	"github.com/ubavic/mint/schema"
+)
+
+const bibtex = `
+Comment outside of entries.
+
+@string{knuth = "Knuth, Donald E."}
+
+@comment{ @article{ignored, title = {Ignored}} }
+
+@book{knuth84,
+  author    = knuth,
+  title     = {The {\TeX}book},
+  publisher = "Addison-Wesley",
+  year      = 1984,
+  month     = jan,
+}
+
+@Article(lamport94,
+  Author = {Lamport, Leslie},
+  Title  = "{LaTeX}: " # {A Document
+            Preparation System},
+  Year   = {1994}
+)
+`
+
+const cslJSON = `[
+  {
+    "id": "knuth84",
+    "type": "book",
+    "author": [{"family": "Knuth", "given": "Donald E."}],
+    "title": "The TeXbook",
+    "issued": {"date-parts": [[1984, 1]]},
+    "page": 483
+  },
+  {
+    "id": "w3c",
+    "type": "webpage",
+    "author": [{"literal": "W3C"}, {"family": "Berners-Lee"}],
+    "issued": {"date-parts": [["2004"]]}
+  }
+]`
+
+func TestLoad(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     string
+		expected []bibliography.Entry
+	}{
+		{
+			name: "references.bib",
+			data: bibtex,
+			expected: []bibliography.Entry{
+				{
+					Key:  "knuth84",
+					Type: "book",
+					Fields: map[string]string{
+						"author":    "Knuth, Donald E.",
+						"title":     "The \\TeXbook",
+						"publisher": "Addison-Wesley",
+						"year":      "1984",
+						"month":     "January",
+					},
+				},
+				{
+					Key:  "lamport94",
+					Type: "article",
+					Fields: map[string]string{
+						"author": "Lamport, Leslie",
+						"title":  "LaTeX: A Document Preparation System",
+						"year":   "1994",
+					},
+				},
+			},
+		},
+		{
+			name: "references.json",
+			data: cslJSON,
+			expected: []bibliography.Entry{
+				{
+					Key:  "knuth84",
+					Type: "book",
+					Fields: map[string]string{
+						"author": "Knuth, Donald E.",
+						"title":  "The TeXbook",
+						"year":   "1984",
+						"page":   "483",
+					},
+				},
+				{
+					Key:  "w3c",
+					Type: "webpage",
+					Fields: map[string]string{
+						"author": "W3C and Berners-Lee",
+						"year":   "2004",
+					},
+				},
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TestLoad%d", i), func(t *testing.T) {
+			b, err := bibliography.Load(tc.name, []byte(tc.data))
+			if err != nil {
+				t.Fatalf("Expected no error, got \"%v\"", err)
+			}
+
+			keys := b.Keys()
+			if len(keys) != len(tc.expected) {
+				t.Fatalf("Expected %d entries, got %v", len(tc.expected), keys)
+			}
+
+			for j, expected := range tc.expected {
+				entry, ok := b.Get(keys[j])
+				if !ok || !reflect.DeepEqual(*entry, expected) {
+					t.Errorf("Expected %v, got %v", expected, entry)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		data          string
+		expectedError error
+		expectedLine  string
+	}{
+		{
+			name:          "a.bib",
+			data:          "@book{a,\n  title = {A}\n\n@book{b, title = {B}}",
+			expectedError: bibliography.ErrInvalidBibliography,
+			expectedLine:  "line 4",
+		},
+		{
+			name:          "a.bib",
+			data:          "@book{a, title = {A}}\n@book{a, title = {B}}",
+			expectedError: bibliography.ErrDuplicateKey,
+			expectedLine:  "line 2",
+		},
+		{
+			name:          "a.bib",
+			data:          "\n\n@book{a, title = undefined}",
+			expectedError: bibliography.ErrInvalidBibliography,
+			expectedLine:  "line 3",
+		},
+		{
+			name:          "a.json",
+			data:          `[{"title": "No id"}]`,
+			expectedError: bibliography.ErrInvalidBibliography,
+		},
+		{
+			name:          "a.yaml",
+			expectedError: bibliography.ErrUnknownFormat,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TestLoadErrors%d", i), func(t *testing.T) {
+			_, err := bibliography.Load(tc.name, []byte(tc.data))
+			if !errors.Is(err, tc.expectedError) {
+				t.Fatalf("Expected error \"%v\", got \"%v\"", tc.expectedError, err)
+			}
+
+			if !strings.Contains(err.Error(), tc.expectedLine) {
+				t.Errorf("Expected \"%s\" in \"%v\"", tc.expectedLine, err)
+			}
+		})
+	}
+}
+
+var testSchema = &schema.Schema{
+	Source: schema.Source{
+		BibliographyCommand: "references",
+		Commands: []schema.Command{
+			{Command: "cite", Arguments: 1, Cite: 1},
+			{Command: "citep", Arguments: 2, Cite: 2},
+			{Command: "references"},
+		},
+	},
+}
+
+func TestCite(t *testing.T) {
+	b, err := bibliography.Load("references.bib", []byte(bibtex+"@misc{unused, title = {Unused}}"))
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	tokenizer := parser.NewTokenizer(bufio.NewReader(strings.NewReader("@cite{lamport94}\n@citep{p. 2}{knuth84, lamport94, missing}\n@cite{missing}")))
+	p := parser.NewParser(tokenizer.Tokenize(), testSchema)
+
+	document, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	citations := bibliography.Cite(testSchema, document, b)
+
+	if citations.Command != "references" {
+		t.Errorf("Expected command \"references\", got \"%s\"", citations.Command)
+	}
+
+	numbers := []string{}
+	for _, command := range document.Content() {
+		if command, ok := command.(*parser.Command); ok {
+			for _, reference := range citations.Of(command) {
+				numbers = append(numbers, fmt.Sprintf("%s=%d", reference.Key, reference.Number))
+			}
+		}
+	}
+
+	expected := "lamport94=1 knuth84=2 lamport94=1 missing=0 missing=0"
+	if strings.Join(numbers, " ") != expected {
+		t.Errorf("Expected \"%s\", got \"%s\"", expected, strings.Join(numbers, " "))
+	}
+
+	if len(citations.References) != 2 || citations.References[1].Fields["publisher"] != "Addison-Wesley" {
+		t.Errorf("Expected two references, got %v", citations.References)
+	}
+
+	if len(citations.Undefined) != 1 || !errors.Is(citations.Undefined[0], bibliography.ErrUndefinedKey) {
+		t.Fatalf("Expected one undefined key, got %v", citations.Undefined)
+	}
+
+	var positionError *parser.Error
+	if !errors.As(citations.Undefined[0], &positionError) || positionError.Position.Line != 2 {
+		t.Errorf("Expected undefined key at line 2, got \"%v\"", citations.Undefined[0])
+	}
+
+	if !reflect.DeepEqual(citations.Unused, []string{"unused"}) {
+		t.Errorf("Expected unused [unused], got %v", citations.Unused)
+	}
+}
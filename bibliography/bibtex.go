@@ -0,0 +1,338 @@
+package bibliography
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+var months = map[string]string{
+	"jan": "January", "feb": "February", "mar": "March", "apr": "April",
+	"may": "May", "jun": "June", "jul": "July", "aug": "August",
+	"sep": "September", "oct": "October", "nov": "November", "dec": "December",
+}
+
+// ParseBibTeX parses a BibTeX file. Text outside entries is ignored, as well
+// as `@comment` and `@preamble`. Macros defined with `@string` and
+// concatenation with `#` are supported. Braces are removed from values, and
+// whitespace is collapsed.
+func ParseBibTeX(data []byte) (*Bibliography, error) {
+	p := bibtexParser{
+		input:  []rune(string(data)),
+		line:   1,
+		macros: map[string]string{},
+	}
+
+	for name, month := range months {
+		p.macros[name] = month
+	}
+
+	b := newBibliography()
+
+	for p.skipTo('@') {
+		p.next()
+
+		entry, err := p.parseEntry()
+		if err != nil {
+			return nil, err
+		}
+
+		if entry == nil {
+			continue
+		}
+
+		err = b.add(entry)
+		if err != nil {
+			return nil, p.error("%w", err)
+		}
+	}
+
+	return b, nil
+}
+
+type bibtexParser struct {
+	input  []rune
+	offset int
+	line   int
+	macros map[string]string
+}
+
+func (p *bibtexParser) error(format string, args ...any) error {
+	return fmt.Errorf("%w: line %d: %w", ErrInvalidBibliography, p.line, fmt.Errorf(format, args...))
+}
+
+func (p *bibtexParser) eof() bool {
+	return p.offset >= len(p.input)
+}
+
+func (p *bibtexParser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+
+	return p.input[p.offset]
+}
+
+func (p *bibtexParser) next() rune {
+	r := p.peek()
+	if r == '\n' {
+		p.line += 1
+	}
+	p.offset += 1
+
+	return r
+}
+
+func (p *bibtexParser) skipSpace() {
+	for !p.eof() && unicode.IsSpace(p.peek()) {
+		p.next()
+	}
+}
+
+// skipTo advances to the rune, and reports whether it was found
+func (p *bibtexParser) skipTo(r rune) bool {
+	for !p.eof() && p.peek() != r {
+		p.next()
+	}
+
+	return !p.eof()
+}
+
+func (p *bibtexParser) expect(r rune) error {
+	p.skipSpace()
+
+	if p.eof() {
+		return p.error("expected %q, got end of file", r)
+	}
+
+	if got := p.next(); got != r {
+		return p.error("expected %q, got %q", r, got)
+	}
+
+	return nil
+}
+
+// identifier reads a name of an entry type, field or macro
+func (p *bibtexParser) identifier() string {
+	p.skipSpace()
+
+	start := p.offset
+	for !p.eof() && !unicode.IsSpace(p.peek()) && !strings.ContainsRune("{}()=,#\"", p.peek()) {
+		p.next()
+	}
+
+	return string(p.input[start:p.offset])
+}
+
+// parseEntry parses an entry after `@`, and returns nil for comments,
+// preambles and macros
+func (p *bibtexParser) parseEntry() (*Entry, error) {
+	kind := strings.ToLower(p.identifier())
+	if kind == "" {
+		return nil, p.error("expected entry type")
+	}
+
+	p.skipSpace()
+	open := p.next()
+
+	var closing rune
+	switch open {
+	case '{':
+		closing = '}'
+	case '(':
+		closing = ')'
+	default:
+		return nil, p.error("expected '{' after @%s", kind)
+	}
+
+	switch kind {
+	case "comment", "preamble":
+		p.offset -= 1
+		_, err := p.braced(open, closing)
+		return nil, err
+	case "string":
+		name, value, err := p.field()
+		if err != nil {
+			return nil, err
+		}
+		p.macros[strings.ToLower(name)] = value
+		return nil, p.expect(closing)
+	}
+
+	p.skipSpace()
+	start := p.offset
+	for !p.eof() && p.peek() != ',' && p.peek() != closing && !unicode.IsSpace(p.peek()) {
+		p.next()
+	}
+
+	entry := &Entry{
+		Key:    string(p.input[start:p.offset]),
+		Type:   kind,
+		Fields: map[string]string{},
+	}
+
+	if entry.Key == "" {
+		return nil, p.error("missing key of @%s", kind)
+	}
+
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return nil, p.error("unterminated entry %s", entry.Key)
+		}
+
+		switch p.next() {
+		case closing:
+			return entry, nil
+		case ',':
+		default:
+			return nil, p.error("expected ',' in entry %s", entry.Key)
+		}
+
+		p.skipSpace()
+		if p.peek() == closing {
+			p.next()
+			return entry, nil
+		}
+
+		name, value, err := p.field()
+		if err != nil {
+			return nil, err
+		}
+
+		entry.Fields[strings.ToLower(name)] = value
+	}
+}
+
+// field parses `name = value`
+func (p *bibtexParser) field() (string, string, error) {
+	name := p.identifier()
+	if name == "" {
+		return "", "", p.error("expected field name")
+	}
+
+	err := p.expect('=')
+	if err != nil {
+		return "", "", err
+	}
+
+	value, err := p.value()
+	if err != nil {
+		return "", "", err
+	}
+
+	return name, value, nil
+}
+
+// value parses parts of a value concatenated with `#`
+func (p *bibtexParser) value() (string, error) {
+	var result strings.Builder
+
+	for {
+		p.skipSpace()
+
+		switch p.peek() {
+		case '{':
+			part, err := p.braced('{', '}')
+			if err != nil {
+				return "", err
+			}
+			result.WriteString(part)
+		case '"':
+			part, err := p.quoted()
+			if err != nil {
+				return "", err
+			}
+			result.WriteString(part)
+		default:
+			name := p.identifier()
+			if name == "" {
+				return "", p.error("expected value")
+			}
+
+			if unicode.IsDigit([]rune(name)[0]) {
+				result.WriteString(name)
+			} else if macro, ok := p.macros[strings.ToLower(name)]; ok {
+				result.WriteString(macro)
+			} else {
+				return "", p.error("undefined macro %s", name)
+			}
+		}
+
+		p.skipSpace()
+		if p.peek() != '#' {
+			return normalize(result.String()), nil
+		}
+		p.next()
+	}
+}
+
+// braced reads balanced braces, and returns the content without them
+func (p *bibtexParser) braced(open, closing rune) (string, error) {
+	line := p.line
+	p.next()
+
+	start := p.offset
+	depth := 1
+
+	for !p.eof() {
+		switch p.next() {
+		case '\\':
+			p.next()
+		case open:
+			depth += 1
+		case closing:
+			depth -= 1
+			if depth == 0 {
+				return string(p.input[start : p.offset-1]), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("%w: line %d: unbalanced braces", ErrInvalidBibliography, line)
+}
+
+// quoted reads a value in quotes, which may contain quotes inside braces
+func (p *bibtexParser) quoted() (string, error) {
+	line := p.line
+	p.next()
+
+	start := p.offset
+	depth := 0
+
+	for !p.eof() {
+		switch p.next() {
+		case '\\':
+			p.next()
+		case '{':
+			depth += 1
+		case '}':
+			depth -= 1
+		case '"':
+			if depth == 0 {
+				return string(p.input[start : p.offset-1]), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("%w: line %d: unterminated quotes", ErrInvalidBibliography, line)
+}
+
+// normalize removes braces that are not escaped, and collapses whitespace
+func normalize(value string) string {
+	var result strings.Builder
+
+	runes := []rune(value)
+	for i := 0; i < len(runes); i++ {
+		switch {
+		case runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("{}", runes[i+1]):
+			i += 1
+			result.WriteRune(runes[i])
+		case runes[i] == '{' || runes[i] == '}':
+		default:
+			result.WriteRune(runes[i])
+		}
+	}
+
+	return strings.Join(strings.Fields(result.String()), " ")
+}
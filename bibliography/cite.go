@@ -0,0 +1,141 @@
+package bibliography
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ubavic/mint/parser"
+	"github.com/ubavic/mint/schema"
+)
+
+var ErrUndefinedKey = errors.New("undefined bibliography key")
+var ErrUnusedKey = errors.New("unused bibliography key")
+
+// Citations holds references cited in a document
+type Citations struct {
+	// Command is replaced with the list of references
+	Command string
+	// References are cited references in the order of first citation
+	References []*schema.Reference
+	// Undefined holds positioned errors for keys not found in the
+	// bibliography
+	Undefined []error
+	// Unused lists keys of entries that are never cited
+	Unused []string
+
+	commands map[*parser.Command][]*schema.Reference
+}
+
+// Cite walks the document in order, and resolves keys of commands with a cite
+// argument. The bibliography may be nil, in which case all keys are
+// undefined.
+func Cite(s *schema.Schema, document parser.Element, b *Bibliography) *Citations {
+	c := citer{
+		schema:       s,
+		bibliography: b,
+		citations: &Citations{
+			Command:    s.Source.BibliographyCommand,
+			References: []*schema.Reference{},
+			Undefined:  []error{},
+			Unused:     []string{},
+			commands:   map[*parser.Command][]*schema.Reference{},
+		},
+		cited: map[string]*schema.Reference{},
+	}
+
+	c.walk(document)
+
+	if b != nil {
+		for _, key := range b.Keys() {
+			if _, ok := c.cited[key]; !ok {
+				c.citations.Unused = append(c.citations.Unused, key)
+			}
+		}
+	}
+
+	return c.citations
+}
+
+// Of returns references cited by the command, in the order of its keys
+func (c *Citations) Of(command *parser.Command) []*schema.Reference {
+	return c.commands[command]
+}
+
+type citer struct {
+	schema       *schema.Schema
+	bibliography *Bibliography
+	citations    *Citations
+	cited        map[string]*schema.Reference
+}
+
+func (c *citer) walk(element parser.Element) {
+	command, ok := element.(*parser.Command)
+	if !ok {
+		for _, node := range element.Content() {
+			c.walk(node)
+		}
+		return
+	}
+
+	definition, err := c.schema.GetCommand(command.Name)
+	if err == nil && definition.Cite > 0 && definition.Cite <= len(command.Arguments) {
+		c.cite(command, parser.PlainText(command.Arguments[definition.Cite-1]))
+	}
+
+	for _, arg := range command.Arguments {
+		c.walk(arg)
+	}
+}
+
+func (c *citer) cite(command *parser.Command, keys string) {
+	references := []*schema.Reference{}
+
+	for _, key := range strings.Split(keys, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+
+		reference, ok := c.cited[key]
+		if !ok {
+			reference = c.resolve(command, key)
+		}
+
+		if !slices.Contains(references, reference) {
+			references = append(references, reference)
+		}
+	}
+
+	c.citations.commands[command] = references
+}
+
+func (c *citer) resolve(command *parser.Command, key string) *schema.Reference {
+	var entry *Entry
+	ok := false
+
+	if c.bibliography != nil {
+		entry, ok = c.bibliography.Get(key)
+	}
+
+	if !ok {
+		// undefined keys are reported once, and not numbered
+		c.citations.Undefined = append(c.citations.Undefined, &parser.Error{Position: command.Position, Err: fmt.Errorf("%w: %s", ErrUndefinedKey, key)})
+		reference := &schema.Reference{Key: key, Fields: map[string]string{}}
+		c.cited[key] = reference
+		return reference
+	}
+
+	reference := &schema.Reference{
+		Key:    key,
+		Type:   entry.Type,
+		Number: len(c.citations.References) + 1,
+		Fields: entry.Fields,
+	}
+
+	c.cited[key] = reference
+	c.citations.References = append(c.citations.References, reference)
+
+	return reference
+}
@@ -0,0 +1,113 @@
+package bibliography
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type cslName struct {
+	Family  string `json:"family"`
+	Given   string `json:"given"`
+	Literal string `json:"literal"`
+}
+
+type cslDate struct {
+	DateParts [][]json.Number `json:"date-parts"`
+	Literal   string          `json:"literal"`
+}
+
+// ParseCSLJSON parses an array of CSL-JSON items. The `id` of an item is its
+// key. Names are converted to "Family, Given" and joined with " and ", and
+// the `issued` date is converted to `year`. Other fields that are strings or
+// numbers are kept under their CSL names.
+func ParseCSLJSON(data []byte) (*Bibliography, error) {
+	var items []map[string]json.RawMessage
+
+	err := json.Unmarshal(data, &items)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBibliography, err)
+	}
+
+	b := newBibliography()
+
+	for i, item := range items {
+		entry, err := cslEntry(item)
+		if err != nil {
+			return nil, fmt.Errorf("%w: item %d: %w", ErrInvalidBibliography, i+1, err)
+		}
+
+		err = b.add(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%w: item %d: %w", ErrInvalidBibliography, i+1, err)
+		}
+	}
+
+	return b, nil
+}
+
+func cslEntry(item map[string]json.RawMessage) (*Entry, error) {
+	entry := &Entry{Fields: map[string]string{}}
+
+	for name, raw := range item {
+		var value any
+		err := json.Unmarshal(raw, &value)
+		if err != nil {
+			return nil, err
+		}
+
+		switch v := value.(type) {
+		case string:
+			entry.Fields[name] = v
+		case float64:
+			entry.Fields[name] = strconv.FormatFloat(v, 'f', -1, 64)
+		case []any:
+			var names []cslName
+			if json.Unmarshal(raw, &names) == nil {
+				entry.Fields[name] = joinNames(names)
+			}
+		case map[string]any:
+			var date cslDate
+			if name == "issued" && json.Unmarshal(raw, &date) == nil {
+				entry.Fields["year"] = date.year()
+			}
+		}
+	}
+
+	entry.Key = entry.Fields["id"]
+	entry.Type = entry.Fields["type"]
+	delete(entry.Fields, "id")
+	delete(entry.Fields, "type")
+
+	if entry.Key == "" {
+		return nil, fmt.Errorf("missing id")
+	}
+
+	return entry, nil
+}
+
+func joinNames(names []cslName) string {
+	result := make([]string, 0, len(names))
+
+	for _, name := range names {
+		switch {
+		case name.Literal != "":
+			result = append(result, name.Literal)
+		case name.Given != "":
+			result = append(result, name.Family+", "+name.Given)
+		default:
+			result = append(result, name.Family)
+		}
+	}
+
+	return strings.Join(result, " and ")
+}
+
+func (d cslDate) year() string {
+	if len(d.DateParts) > 0 && len(d.DateParts[0]) > 0 {
+		return d.DateParts[0][0].String()
+	}
+
+	return d.Literal
+}
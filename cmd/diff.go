@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ubavic/mint/diff"
+	"github.com/ubavic/mint/parser"
+)
+
+func runDiff(args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	schemaFileFlag := flags.String("schema", "", "Validate files against a schema file")
+	jsonFlag := flags.Bool("json", false, "Print changes as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: mint diff [-schema schema.yaml] [-json] old.atex new.atex")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	validator, err := optionalValidator(*schemaFileFlag)
+	if err != nil {
+		fail("%v", err)
+	}
+
+	oldFileName, newFileName := flags.Arg(0), flags.Arg(1)
+
+	oldDoc, err := parseFile(oldFileName, validator)
+	if err != nil {
+		fail("%v", err)
+	}
+
+	newDoc, err := parseFile(newFileName, validator)
+	if err != nil {
+		fail("%v", err)
+	}
+
+	changes := diff.Compare(oldDoc, newDoc)
+
+	if *jsonFlag {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(changes)
+		return
+	}
+
+	for _, change := range changes {
+		location := ""
+		switch change.Kind {
+		case diff.Added:
+			location = fileLocation(newFileName, change.NewPosition)
+		case diff.Removed:
+			location = fileLocation(oldFileName, change.OldPosition)
+		default:
+			location = fileLocation(oldFileName, change.OldPosition) + " -> " + fileLocation(newFileName, change.NewPosition)
+		}
+
+		fmt.Printf("%s %s %s", change.Kind, change.Node, location)
+		if len(change.Path) > 0 {
+			fmt.Printf(" in @%s", strings.Join(change.Path, " > @"))
+		}
+		fmt.Println()
+
+		if change.OldContent != "" {
+			fmt.Printf("  - %s\n", change.OldContent)
+		}
+		if change.NewContent != "" {
+			fmt.Printf("  + %s\n", change.NewContent)
+		}
+	}
+}
+
+func fileLocation(fileName string, position *parser.Position) string {
+	return fmt.Sprintf("%s:%s", fileName, position)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ubavic/mint"
+	"github.com/ubavic/mint/bibliography"
+	"github.com/ubavic/mint/parser"
+	"github.com/ubavic/mint/schema"
+)
+
+// loadSchema loads the schema and its imports
+func loadSchema(fileName string) (*schema.Schema, error) {
+	fsys, name := schemaFS(fileName)
+
+	newSchema, err := schema.LoadFS(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema \"%s\": %w", fileName, err)
+	}
+
+	return newSchema, nil
+}
+
+// schemaFS returns a file system holding the schema, and the name of the
+// schema in it. Imports may refer to any file, so names in the file system
+// are paths relative to the working directory, as they are shown in errors
+// and issues.
+func schemaFS(fileName string) (fs.FS, string) {
+	return workingDirFS{}, filepath.ToSlash(displayName(fileName))
+}
+
+// workingDirFS opens files by paths relative to the working directory, or by
+// absolute paths. Unlike os.DirFS, it accepts paths with "..", which imports
+// of schemas may use.
+type workingDirFS struct{}
+
+func (workingDirFS) Open(name string) (fs.File, error) {
+	return os.Open(filepath.FromSlash(name))
+}
+
+// displayName converts a path to one relative to the working directory, if
+// possible
+func displayName(path string) string {
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return absolute
+	}
+
+	relative, err := filepath.Rel(wd, absolute)
+	if err != nil {
+		return absolute
+	}
+
+	return relative
+}
+
+func loadBibliography(fileName string) (*bibliography.Bibliography, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("can't open file \"%s\": %w", fileName, err)
+	}
+
+	references, err := bibliography.Load(fileName, data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bibliography \"%s\": %w", fileName, err)
+	}
+
+	return references, nil
+}
+
+func parseFile(fileName string, validator parser.Validator) (*parser.Block, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("can't open file \"%s\": %w", fileName, err)
+	}
+	defer file.Close()
+
+	tokenizer := parser.NewTokenizer(bufio.NewReader(file))
+	tokens := tokenizer.Tokenize()
+
+	p := parser.NewParser(tokens, validator)
+	doc, err := p.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing \"%s\": %w", fileName, err)
+	}
+
+	return doc, nil
+}
+
+// optionalValidator returns a schema validator if the schema file is given
+func optionalValidator(schemaFileName string) (parser.Validator, error) {
+	if schemaFileName == "" {
+		return &parser.OptimisticValidator{}, nil
+	}
+
+	return loadSchema(schemaFileName)
+}
+
+// targetNames splits a comma separated list of targets. "all" selects every
+// target of the schema, and an empty list selects the default target.
+func targetNames(s *schema.Schema, list string) ([]string, error) {
+	if list == "" {
+		return []string{""}, nil
+	}
+
+	if list == "all" {
+		if len(s.Targets) == 0 {
+			return nil, fmt.Errorf("schema has no targets")
+		}
+
+		names := make([]string, len(s.Targets))
+		for i, target := range s.Targets {
+			names[i] = target.Name
+		}
+		return names, nil
+	}
+
+	names := []string{}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("empty target name in \"%s\"", list)
+		}
+
+		if slices.Contains(names, name) {
+			return nil, fmt.Errorf("target %s is selected more than once", name)
+		}
+
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+// outputFile creates <basename>.<extension> in the directory for each target.
+// Targets without an extension use their lowercase name. When targets share
+// an extension, their files are named <basename>.<target>.<extension>.
+func outputFile(directory, inputFileName string, s *schema.Schema, targets []string) (mint.Output, error) {
+	base := strings.TrimSuffix(filepath.Base(inputFileName), filepath.Ext(inputFileName))
+
+	names := make([]string, len(targets))
+	extensions := make([]string, len(targets))
+	count := map[string]int{}
+	for i, name := range targets {
+		target, err := s.GetTarget(name)
+		if err != nil && name != "" {
+			return nil, fmt.Errorf("%w: %s", err, name)
+		} else if err != nil {
+			return nil, err
+		}
+
+		names[i] = target.Name
+		extensions[i] = target.Extension
+		if extensions[i] == "" {
+			extensions[i] = strings.ToLower(target.Name)
+		}
+		count[extensions[i]]++
+	}
+
+	paths := map[string]string{}
+	owners := map[string]string{}
+	for i, name := range names {
+		fileName := base + "." + extensions[i]
+		if count[extensions[i]] > 1 {
+			fileName = base + "." + strings.ToLower(name) + "." + extensions[i]
+		}
+
+		path := filepath.Join(directory, fileName)
+		if owner, ok := owners[path]; ok {
+			return nil, fmt.Errorf("targets %s and %s are written to the same file \"%s\"", owner, name, path)
+		}
+
+		owners[path] = name
+		paths[name] = path
+	}
+
+	return func(target *schema.Target) (io.WriteCloser, error) {
+		return os.Create(paths[target.Name])
+	}, nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// metadataFlag collects repeated `-meta key=value` flags
+type metadataFlag map[string]string
+
+func (m metadataFlag) String() string {
+	pairs := []string{}
+	for key, value := range m {
+		pairs = append(pairs, key+"="+value)
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func (m metadataFlag) Set(value string) error {
+	key, val, found := strings.Cut(value, "=")
+	if !found || key == "" {
+		return fmt.Errorf("expected key=value, got \"%s\"", value)
+	}
+
+	m[key] = val
+
+	return nil
+}
@@ -1,72 +1,130 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 
-	"github.com/ubavic/mint/parser"
-	"github.com/ubavic/mint/schema"
-	"github.com/ubavic/mint/writer"
-	"gopkg.in/yaml.v3"
+	"github.com/ubavic/mint"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "query":
+			runQuery(os.Args[2:])
+			return
+		case "diff":
+			runDiff(os.Args[2:])
+			return
+		case "schema":
+			runSchema(os.Args[2:])
+			return
+		}
+	}
+
 	inputFileFlag := flag.String("in", "", "Specifies a input file")
 	schemaFileFlag := flag.String("schema", "", "Specifies a schema file")
-	targetFlag := flag.String("target", "", "Select target from schema")
+	targetFlag := flag.String("target", "", "Select targets from schema, separated by commas, or all")
+	outFlag := flag.String("out", "", "Write each target to <basename>.<extension> in the directory")
+	bibliographyFileFlag := flag.String("bib", "", "Specifies a BibTeX or CSL-JSON bibliography file")
+	metadata := metadataFlag{}
+	flag.Var(metadata, "meta", "Set document metadata as key=value (repeatable)")
 	flag.Parse()
 
 	if *inputFileFlag == "" {
-		fmt.Println("Expected input file")
-		return
+		fail("Expected input file")
 	}
 
 	if *schemaFileFlag == "" {
-		fmt.Println("Expected schema file")
-		return
+		fail("Expected schema file")
 	}
 
-	schemaFile, err := os.ReadFile(*schemaFileFlag)
+	newSchema, err := loadSchema(*schemaFileFlag)
 	if err != nil {
-		fmt.Printf("Can't open file \"%s\": %v", *schemaFileFlag, err.Error())
-		return
+		fail("%v", err)
 	}
 
-	file, err := os.Open(*inputFileFlag)
+	targets, err := targetNames(newSchema, *targetFlag)
 	if err != nil {
-		fmt.Printf("Can't open file \"%s\": %v", *inputFileFlag, err.Error())
-		return
+		fail("%v", err)
 	}
 
-	var newSchema schema.Schema
+	if len(targets) > 1 && *outFlag == "" {
+		fail("Expected output directory for multiple targets")
+	}
 
-	err = yaml.Unmarshal(schemaFile, &newSchema)
-	if err != nil {
-		fmt.Printf("Can't unmarshal schema: %v", err.Error())
-		return
+	options := []mint.Option{
+		mint.WithMetadata(metadata),
+		mint.WithIncludeResolver(mint.FSResolver(os.DirFS(filepath.Dir(*inputFileFlag)))),
+		mint.WithDiagnostics(printWarning),
 	}
 
-	fileBuf := bufio.NewReader(file)
-	tokenizer := parser.NewTokenizer(fileBuf)
+	if *bibliographyFileFlag != "" {
+		references, err := loadBibliography(*bibliographyFileFlag)
+		if err != nil {
+			fail("%v", err)
+		}
 
-	tokens := tokenizer.Tokenize()
+		options = append(options, mint.WithBibliography(references))
+	}
 
-	parser := parser.NewParser(tokens, newSchema)
-	doc, err := parser.Parse()
+	file, err := os.Open(*inputFileFlag)
 	if err != nil {
-		fmt.Printf("Error while parsing \"%s\": %v", *inputFileFlag, err.Error())
+		fail("Can't open file \"%s\": %v", *inputFileFlag, err.Error())
+	}
+	defer file.Close()
+
+	if *outFlag == "" {
+		err = mint.Compile(
+			context.Background(),
+			os.Stdout,
+			file,
+			newSchema,
+			append(options, mint.WithTarget(targets[0]))...,
+		)
+		if err != nil {
+			fail("Error while compiling \"%s\": %v", *inputFileFlag, err.Error())
+		}
+
+		fmt.Println()
 		return
 	}
 
-	target, err := newSchema.GetTarget(*targetFlag)
+	output, err := outputFile(*outFlag, *inputFileFlag, newSchema, targets)
 	if err != nil {
-		fmt.Printf("Can't find target: %v", err.Error())
-		return
+		fail("%v", err)
+	}
+
+	err = os.MkdirAll(*outFlag, 0o755)
+	if err != nil {
+		fail("Can't create directory \"%s\": %v", *outFlag, err.Error())
 	}
 
-	rendered := writer.Write(target, doc)
+	err = mint.CompileTargets(
+		context.Background(),
+		file,
+		newSchema,
+		targets,
+		output,
+		options...,
+	)
+	if err != nil {
+		fail("Error while compiling \"%s\": %v", *inputFileFlag, err.Error())
+	}
+}
 
-	fmt.Println(rendered)
+// fail prints the message to stderr, and exits with status 1
+func fail(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
+
+// printWarning prints warnings to stderr. Errors are returned by Compile.
+func printWarning(d mint.Diagnostic) {
+	if d.Severity == mint.SeverityWarning {
+		fmt.Fprintln(os.Stderr, d)
+	}
 }
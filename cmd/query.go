@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ubavic/mint/parser"
+	"github.com/ubavic/mint/query"
+)
+
+func runQuery(args []string) {
+	flags := flag.NewFlagSet("query", flag.ExitOnError)
+	schemaFileFlag := flags.String("schema", "", "Validate files against a schema file")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: mint query [-schema schema.yaml] selector file.atex...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() < 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	selector, err := query.Compile(flags.Arg(0))
+	if err != nil {
+		fail("Can't compile selector: %v", err.Error())
+	}
+
+	validator, err := optionalValidator(*schemaFileFlag)
+	if err != nil {
+		fail("%v", err)
+	}
+
+	for _, fileName := range flags.Args()[1:] {
+		doc, err := parseFile(fileName, validator)
+		if err != nil {
+			fail("%v", err)
+		}
+
+		for _, match := range selector.Find(doc) {
+			fmt.Printf("%s:%s: %s\n", fileName, match.Command.Position, parser.Format(match.Command))
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ubavic/mint/parser"
+	"github.com/ubavic/mint/schema"
+	"github.com/ubavic/mint/schemadoc"
+)
+
+func runSchema(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: mint schema lint|jsonschema|migrate|doc")
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "lint":
+		runSchemaLint(args[1:])
+	case "jsonschema":
+		runSchemaJSONSchema()
+	case "migrate":
+		runSchemaMigrate(args[1:])
+	case "doc":
+		runSchemaDoc(args[1:])
+	default:
+		fail("Unknown schema command \"%s\"", args[0])
+	}
+}
+
+func runSchemaLint(args []string) {
+	flags := flag.NewFlagSet("schema lint", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: mint schema lint schema.yaml...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	found := false
+
+	for _, fileName := range flags.Args() {
+		fsys, name := schemaFS(fileName)
+
+		issues, err := schema.LintFS(fsys, name)
+		if err != nil {
+			fail("invalid schema \"%s\": %v", fileName, err.Error())
+		}
+
+		for _, issue := range issues {
+			if issue.File == "" {
+				issue.File = name
+			}
+
+			fmt.Println(issue)
+			found = true
+		}
+	}
+
+	if found {
+		os.Exit(1)
+	}
+}
+
+func runSchemaJSONSchema() {
+	data, err := schema.JSONSchema()
+	if err != nil {
+		fail("%v", err)
+	}
+
+	fmt.Println(string(data))
+}
+
+func runSchemaMigrate(args []string) {
+	flags := flag.NewFlagSet("schema migrate", flag.ExitOnError)
+	writeFlag := flags.Bool("w", false, "Write the migrated schema to the file instead of printing it")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: mint schema migrate [-w] schema.yaml")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	fileName := flags.Arg(0)
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		fail("Can't read file \"%s\": %v", fileName, err.Error())
+	}
+
+	migrated, applied, err := schema.Migrate(data)
+	if err != nil {
+		fail("Can't migrate schema \"%s\": %v", fileName, err.Error())
+	}
+
+	for _, description := range applied {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", fileName, description)
+	}
+
+	if !*writeFlag {
+		fmt.Print(string(migrated))
+		return
+	}
+
+	if len(applied) == 0 {
+		return
+	}
+
+	err = os.WriteFile(fileName, migrated, 0644)
+	if err != nil {
+		fail("Can't write file \"%s\": %v", fileName, err.Error())
+	}
+}
+
+func runSchemaDoc(args []string) {
+	flags := flag.NewFlagSet("schema doc", flag.ExitOnError)
+	formatFlag := flags.String("format", "markdown", "Output format: markdown, html or atex")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: mint schema doc [-format markdown|html|atex] schema.yaml")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	s, err := loadSchema(flags.Arg(0))
+	if err != nil {
+		fail("%v", err)
+	}
+
+	ctx := context.Background()
+
+	switch strings.ToLower(*formatFlag) {
+	case "markdown":
+		err = schemadoc.Write(ctx, os.Stdout, s, "Markdown")
+	case "html":
+		err = schemadoc.Write(ctx, os.Stdout, s, "HTML")
+	case "atex":
+		var page *parser.Block
+		page, err = schemadoc.Document(ctx, s)
+		if err == nil {
+			fmt.Print(parser.Format(page))
+		}
+	default:
+		fail("Unknown format \"%s\"", *formatFlag)
+	}
+
+	if err != nil {
+		fail("%v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package mint
+
+import (
+	"errors"
+
+	"github.com/ubavic/mint/parser"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "unknown"
+	}
+}
+
+type Diagnostic struct {
+	Severity Severity
+	// File is the name of an included file, or empty for the main document
+	File     string
+	Position parser.Position
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	location := d.Position.String()
+	if d.File != "" {
+		location = d.File + ":" + location
+	}
+
+	return location + ": " + d.Severity.String() + ": " + d.Message
+}
+
+type DiagnosticSink func(Diagnostic)
+
+func (c *config) report(d Diagnostic) {
+	if c.diagnostics != nil {
+		c.diagnostics(d)
+	}
+}
+
+// reportError sends the error to the diagnostic sink and returns it
+func (c *config) reportError(file string, err error) error {
+	d := Diagnostic{Severity: SeverityError, File: file, Message: err.Error()}
+
+	var positionError *parser.Error
+	if errors.As(err, &positionError) {
+		d.Position = positionError.Position
+		d.Message = positionError.Err.Error()
+	}
+
+	var includeError *IncludeError
+	if errors.As(err, &includeError) {
+		d.File = includeError.File
+	}
+
+	c.report(d)
+
+	return err
+}
@@ -0,0 +1,325 @@
+// Package diff compares two parsed documents structurally.
+//
+// Text is compared after whitespace normalization, so prose re-wrapped across
+// lines is not reported. Nodes at the same level are aligned by a longest
+// common subsequence, unmatched commands with the same name are compared
+// recursively, and identical nodes that changed place are reported as moved.
+package diff
+
+import (
+	"strings"
+
+	"github.com/ubavic/mint/parser"
+)
+
+type Kind string
+
+const (
+	Added    Kind = "added"
+	Removed  Kind = "removed"
+	Moved    Kind = "moved"
+	Modified Kind = "modified"
+)
+
+type Change struct {
+	Kind Kind `json:"kind"`
+	// Node is "@name" for commands and "text" for text content
+	Node string `json:"node"`
+	// Path lists the names of enclosing commands
+	Path        []string         `json:"path"`
+	OldPosition *parser.Position `json:"oldPosition,omitempty"`
+	NewPosition *parser.Position `json:"newPosition,omitempty"`
+	OldContent  string           `json:"oldContent,omitempty"`
+	NewContent  string           `json:"newContent,omitempty"`
+}
+
+type entry struct {
+	element parser.Element
+	path    []string
+}
+
+type differ struct {
+	changes    []Change
+	removed    []entry
+	added      []entry
+	signatures map[parser.Element]string
+}
+
+// Compare returns changes needed to transform the old document into the new
+// one. Modifications are listed first, followed by moves, removals and
+// additions.
+func Compare(oldDocument, newDocument parser.Element) []Change {
+	d := differ{
+		changes:    []Change{},
+		signatures: map[parser.Element]string{},
+	}
+
+	d.compareBlocks(content(oldDocument), content(newDocument), []string{})
+	d.resolveMoves()
+
+	return d.changes
+}
+
+func (d *differ) compareBlocks(oldNodes, newNodes []parser.Element, path []string) {
+	oldNodes = significant(oldNodes)
+	newNodes = significant(newNodes)
+
+	oldSignatures := make([]string, len(oldNodes))
+	for i, node := range oldNodes {
+		oldSignatures[i] = d.signature(node)
+	}
+
+	newSignatures := make([]string, len(newNodes))
+	for i, node := range newNodes {
+		newSignatures[i] = d.signature(node)
+	}
+
+	i, j := 0, 0
+	for _, pair := range lcs(oldSignatures, newSignatures) {
+		d.compareGap(oldNodes[i:pair[0]], newNodes[j:pair[1]], path)
+		i, j = pair[0]+1, pair[1]+1
+	}
+	d.compareGap(oldNodes[i:], newNodes[j:], path)
+}
+
+// compareGap pairs nodes between two aligned anchors. Commands with the same
+// name and text nodes are paired in order, and compared recursively.
+func (d *differ) compareGap(oldNodes, newNodes []parser.Element, path []string) {
+	used := make([]bool, len(newNodes))
+
+	for _, oldNode := range oldNodes {
+		paired := false
+
+		for j, newNode := range newNodes {
+			if used[j] || nodeName(oldNode) != nodeName(newNode) {
+				continue
+			}
+
+			used[j] = true
+			paired = true
+			d.compareNodes(oldNode, newNode, path)
+			break
+		}
+
+		if !paired {
+			d.removed = append(d.removed, entry{element: oldNode, path: path})
+		}
+	}
+
+	for j, newNode := range newNodes {
+		if !used[j] {
+			d.added = append(d.added, entry{element: newNode, path: path})
+		}
+	}
+}
+
+func (d *differ) compareNodes(oldNode, newNode parser.Element, path []string) {
+	oldCommand, ok := oldNode.(*parser.Command)
+	if !ok {
+		d.changes = append(d.changes, Change{
+			Kind:        Modified,
+			Node:        nodeName(oldNode),
+			Path:        path,
+			OldPosition: position(oldNode),
+			NewPosition: position(newNode),
+			OldContent:  d.signature(oldNode),
+			NewContent:  d.signature(newNode),
+		})
+		return
+	}
+
+	newCommand := newNode.(*parser.Command)
+
+	if len(oldCommand.Arguments) != len(newCommand.Arguments) {
+		d.changes = append(d.changes, Change{
+			Kind:        Modified,
+			Node:        nodeName(oldNode),
+			Path:        path,
+			OldPosition: position(oldNode),
+			NewPosition: position(newNode),
+			OldContent:  parser.Format(oldNode),
+			NewContent:  parser.Format(newNode),
+		})
+		return
+	}
+
+	innerPath := append(path[:len(path):len(path)], oldCommand.Name)
+	for i := range oldCommand.Arguments {
+		d.compareBlocks(content(oldCommand.Arguments[i]), content(newCommand.Arguments[i]), innerPath)
+	}
+}
+
+// resolveMoves reports removed and added nodes with equal content as moved
+func (d *differ) resolveMoves() {
+	used := make([]bool, len(d.added))
+
+	for _, removed := range d.removed {
+		signature := d.signature(removed.element)
+		moved := false
+
+		for j, added := range d.added {
+			if used[j] || d.signature(added.element) != signature {
+				continue
+			}
+
+			used[j] = true
+			moved = true
+			d.changes = append(d.changes, Change{
+				Kind:        Moved,
+				Node:        nodeName(removed.element),
+				Path:        added.path,
+				OldPosition: position(removed.element),
+				NewPosition: position(added.element),
+				OldContent:  d.content(removed.element),
+			})
+			break
+		}
+
+		if !moved {
+			d.changes = append(d.changes, Change{
+				Kind:        Removed,
+				Node:        nodeName(removed.element),
+				Path:        removed.path,
+				OldPosition: position(removed.element),
+				OldContent:  d.content(removed.element),
+			})
+		}
+	}
+
+	for j, added := range d.added {
+		if !used[j] {
+			d.changes = append(d.changes, Change{
+				Kind:        Added,
+				Node:        nodeName(added.element),
+				Path:        added.path,
+				NewPosition: position(added.element),
+				NewContent:  d.content(added.element),
+			})
+		}
+	}
+}
+
+func (d *differ) content(element parser.Element) string {
+	if _, ok := element.(*parser.Command); ok {
+		return parser.Format(element)
+	}
+
+	return d.signature(element)
+}
+
+// signature is a whitespace insensitive representation of the element
+func (d *differ) signature(element parser.Element) string {
+	if signature, ok := d.signatures[element]; ok {
+		return signature
+	}
+
+	var signature string
+
+	switch v := element.(type) {
+	case *parser.TextContent:
+		signature = normalize(parser.Format(v))
+	case *parser.Command:
+		signature = "@" + v.Name
+		for _, arg := range v.Arguments {
+			signature += "{" + d.signature(arg) + "}"
+		}
+	default:
+		parts := []string{}
+		for _, node := range significant(element.Content()) {
+			parts = append(parts, d.signature(node))
+		}
+		signature = strings.Join(parts, " ")
+	}
+
+	d.signatures[element] = signature
+
+	return signature
+}
+
+func normalize(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func significant(nodes []parser.Element) []parser.Element {
+	result := []parser.Element{}
+
+	for _, node := range nodes {
+		if tc, ok := node.(*parser.TextContent); ok && normalize(tc.TextContent) == "" {
+			continue
+		}
+		result = append(result, node)
+	}
+
+	return result
+}
+
+func content(element parser.Element) []parser.Element {
+	if element == nil {
+		return []parser.Element{}
+	}
+
+	return element.Content()
+}
+
+func nodeName(element parser.Element) string {
+	if command, ok := element.(*parser.Command); ok {
+		return "@" + command.Name
+	}
+
+	return "text"
+}
+
+// position returns the position of the element, or nil when it has none,
+// like elements built in code
+func position(element parser.Element) *parser.Position {
+	var p parser.Position
+
+	switch v := element.(type) {
+	case *parser.Command:
+		p = v.Position
+	case *parser.TextContent:
+		p = v.Position
+	case *parser.Block:
+		p = v.Position
+	}
+
+	if !p.IsValid() {
+		return nil
+	}
+
+	return &p
+}
+
+// lcs returns index pairs of the longest common subsequence of a and b
+func lcs(a, b []string) [][2]int {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	pairs := [][2]int{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			pairs = append(pairs, [2]int{i, j})
+			i++
+			j++
+		} else if lengths[i+1][j] >= lengths[i][j+1] {
+			i++
+		} else {
+			j++
+		}
+	}
+
+	return pairs
+}
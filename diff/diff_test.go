@@ -0,0 +1,130 @@
+package diff_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ubavic/mint/diff"
+	"github.com/ubavic/mint/parser"
+)
+
+func parse(t *testing.T, input string) *parser.Block {
+	tokenizer := parser.NewTokenizer(bufio.NewReader(strings.NewReader(input)))
+	p := parser.NewParser(tokenizer.Tokenize(), &parser.OptimisticValidator{})
+
+	block, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%s\"", err)
+	}
+
+	return block
+}
+
+func TestCompare(t *testing.T) {
+	testCases := []struct {
+		old             string
+		new             string
+		expectedChanges []string
+	}{
+		{
+			old:             "@p{Lorem ipsum\ndolor sit amet}",
+			new:             "@p{Lorem\nipsum dolor    sit amet}",
+			expectedChanges: []string{},
+		},
+		{
+			old:             "@p{a}\n@p{b}",
+			new:             "@p{a}\n@p{b}\n@todo{c}",
+			expectedChanges: []string{"added @todo - 3:1"},
+		},
+		{
+			old:             "@title{T}\n@p{a}\n@p{b}",
+			new:             "@p{a}\n@p{b}",
+			expectedChanges: []string{"removed @title 1:1 -"},
+		},
+		{
+			old:             "@p{a @b{x} c}",
+			new:             "@p{a @b{y} c}",
+			expectedChanges: []string{"modified text 1:9 1:9"},
+		},
+		{
+			old:             "@p{a @b{x} c}",
+			new:             "@p{a @link{x}{y} c}",
+			expectedChanges: []string{"removed @b 1:6 -", "added @link - 1:6"},
+		},
+		{
+			old:             "@link{x}{y}",
+			new:             "@link{x}",
+			expectedChanges: []string{"modified @link 1:1 1:1"},
+		},
+		{
+			old:             "@p{first}\n@p{second}\n@p{third}",
+			new:             "@p{third}\n@p{first}\n@p{second}",
+			expectedChanges: []string{"moved @p 3:1 1:1"},
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(
+			fmt.Sprintf("TestCompare%d", i),
+			func(t *testing.T) {
+				changes := diff.Compare(parse(t, testCase.old), parse(t, testCase.new))
+
+				result := []string{}
+				for _, change := range changes {
+					old, new := "-", "-"
+					if change.OldPosition != nil {
+						old = change.OldPosition.String()
+					}
+					if change.NewPosition != nil {
+						new = change.NewPosition.String()
+					}
+					result = append(result, fmt.Sprintf("%s %s %s %s", change.Kind, change.Node, old, new))
+				}
+
+				if strings.Join(result, ", ") != strings.Join(testCase.expectedChanges, ", ") {
+					t.Errorf("Expected changes %v, got %v", testCase.expectedChanges, result)
+				}
+			},
+		)
+	}
+}
+
+func TestCompareJSON(t *testing.T) {
+	testCases := []struct {
+		old      parser.Element
+		new      parser.Element
+		expected string
+	}{
+		{
+			old:      parse(t, "@p{a}"),
+			new:      parse(t, "@p{a}@q{b}"),
+			expected: `[{"kind":"added","node":"@q","path":[],"newPosition":{"line":1,"column":6},"newContent":"@q{b}"}]`,
+		},
+		{
+			old:      parse(t, "@p{a}@q{b}"),
+			new:      parse(t, "@p{a}"),
+			expected: `[{"kind":"removed","node":"@q","path":[],"oldPosition":{"line":1,"column":6},"oldContent":"@q{b}"}]`,
+		},
+		{
+			old:      &parser.Block{Nodes: []parser.Element{&parser.TextContent{TextContent: "a"}}},
+			new:      &parser.Block{Nodes: []parser.Element{&parser.TextContent{TextContent: "b"}}},
+			expected: `[{"kind":"modified","node":"text","path":[],"oldContent":"a","newContent":"b"}]`,
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(fmt.Sprintf("TestCompareJSON%d", i), func(t *testing.T) {
+			result, err := json.Marshal(diff.Compare(testCase.old, testCase.new))
+			if err != nil {
+				t.Fatalf("Expected no error, got \"%v\"", err)
+			}
+
+			if string(result) != testCase.expected {
+				t.Errorf("Expected %s, got %s", testCase.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+// Package mint is the stable entry point for embedding Mint.
+//
+// A document is compiled with a single call:
+//
+//	err := mint.Compile(ctx, os.Stdout, file, schema,
+//		mint.WithTarget("HTML"),
+//		mint.WithIncludeResolver(mint.FSResolver(os.DirFS("docs"))),
+//	)
+//
+// # Compatibility
+//
+// Mint follows semantic versioning. Within a major version, exported
+// identifiers of this package are not removed, and their signatures and
+// documented behavior do not change in incompatible ways. New options and
+// new fields in structs returned by this package may be added in minor
+// releases, so callers should not rely on the number of fields or compare
+// option values.
+//
+// Rendered output for a given document, schema and target may change only
+// when a bug is fixed. Wording of error messages and diagnostics is not part
+// of the compatibility guarantee, but sentinel errors exported by this
+// package and by the schema and parser packages are.
+//
+// The parser, schema and writer packages are used by this package and are
+// exported for tools that need access to the document tree. Their APIs may
+// still change between minor releases before version 1.0.
+package mint
@@ -0,0 +1,141 @@
+package mint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"slices"
+	"strings"
+
+	"github.com/ubavic/mint/parser"
+	"github.com/ubavic/mint/schema"
+)
+
+var ErrNoIncludeResolver = errors.New("include resolver is not set")
+var ErrIncludeCycle = errors.New("include cycle")
+var ErrInvalidInclude = errors.New("invalid include")
+
+// IncludeResolver opens files referenced by include commands
+type IncludeResolver interface {
+	Resolve(ctx context.Context, name string) (io.ReadCloser, error)
+}
+
+type fsResolver struct {
+	fsys fs.FS
+}
+
+// FSResolver resolves includes as paths in the file system
+func FSResolver(fsys fs.FS) IncludeResolver {
+	return fsResolver{fsys: fsys}
+}
+
+func (r fsResolver) Resolve(ctx context.Context, name string) (io.ReadCloser, error) {
+	return r.fsys.Open(name)
+}
+
+// IncludeError is an error in an included file
+type IncludeError struct {
+	File string
+	Err  error
+}
+
+func (e *IncludeError) Error() string {
+	return e.File + ": " + e.Err.Error()
+}
+
+func (e *IncludeError) Unwrap() error {
+	return e.Err
+}
+
+type includer struct {
+	config *config
+	schema *schema.Schema
+	stack  []string
+}
+
+// expand replaces include commands in the block with contents of included
+// files
+func (inc *includer) expand(ctx context.Context, block *parser.Block) error {
+	nodes := []parser.Element{}
+
+	for _, node := range block.Nodes {
+		command, ok := node.(*parser.Command)
+		if !ok {
+			nodes = append(nodes, node)
+			continue
+		}
+
+		definition, err := inc.schema.GetCommand(command.Name)
+		if err != nil || !definition.Include {
+			for _, arg := range command.Arguments {
+				if argBlock, ok := arg.(*parser.Block); ok {
+					err := inc.expand(ctx, argBlock)
+					if err != nil {
+						return err
+					}
+				}
+			}
+
+			nodes = append(nodes, node)
+			continue
+		}
+
+		included, err := inc.include(ctx, command)
+		if err != nil {
+			return err
+		}
+
+		nodes = append(nodes, included.Nodes...)
+	}
+
+	block.Nodes = nodes
+
+	return nil
+}
+
+func (inc *includer) include(ctx context.Context, command *parser.Command) (*parser.Block, error) {
+	if len(command.Arguments) != 1 {
+		return nil, &parser.Error{Position: command.Position, Err: fmt.Errorf("%w: command %s must have exactly one argument", ErrInvalidInclude, command.Name)}
+	}
+
+	name := strings.TrimSpace(parser.PlainText(command.Arguments[0]))
+
+	if inc.config.resolver == nil {
+		return nil, &parser.Error{Position: command.Position, Err: fmt.Errorf("%w: can't include \"%s\"", ErrNoIncludeResolver, name)}
+	}
+
+	maxDepth := inc.config.limits.MaxIncludeDepth
+	if maxDepth > 0 && len(inc.stack) >= maxDepth {
+		return nil, &parser.Error{Position: command.Position, Err: &parser.LimitError{Limit: "include depth", Max: maxDepth}}
+	}
+
+	if slices.Contains(inc.stack, name) {
+		return nil, &parser.Error{Position: command.Position, Err: fmt.Errorf("%w: %s -> %s", ErrIncludeCycle, strings.Join(inc.stack, " -> "), name)}
+	}
+
+	file, err := inc.config.resolver.Resolve(ctx, name)
+	if err != nil {
+		return nil, &parser.Error{Position: command.Position, Err: fmt.Errorf("can't include \"%s\": %w", name, err)}
+	}
+	defer file.Close()
+
+	block, err := parse(ctx, file, inc.schema, inc.config.limits)
+	if err == nil {
+		inc.stack = append(inc.stack, name)
+		err = inc.expand(ctx, block)
+		inc.stack = inc.stack[:len(inc.stack)-1]
+	}
+
+	if err != nil {
+		var includeError *IncludeError
+		if errors.As(err, &includeError) {
+			return nil, err
+		}
+
+		return nil, &IncludeError{File: name, Err: err}
+	}
+
+	return block, nil
+}
@@ -0,0 +1,204 @@
+package mint
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ubavic/mint/bibliography"
+	"github.com/ubavic/mint/numbering"
+	"github.com/ubavic/mint/outline"
+	"github.com/ubavic/mint/parser"
+	"github.com/ubavic/mint/schema"
+	"github.com/ubavic/mint/writer"
+)
+
+// Compile parses the source document, validates it against the schema and
+// writes it rendered for the selected target to dst. Output is streamed, so
+// dst may receive a part of the document before an error is returned.
+func Compile(ctx context.Context, dst io.Writer, src io.Reader, s *schema.Schema, opts ...Option) error {
+	c := newConfig(opts)
+
+	output := func(*schema.Target) (io.WriteCloser, error) {
+		return nopCloser{dst}, nil
+	}
+
+	return compile(ctx, src, s, []string{c.target}, output, opts)
+}
+
+// Output opens the destination of a rendered target. It is closed after the
+// target is written.
+type Output func(target *schema.Target) (io.WriteCloser, error)
+
+// CompileTargets is like Compile, but renders the document for each of the
+// named targets, while the source is parsed once. WithTarget is ignored.
+// Targets are written in the given order, and writing stops at the first
+// error.
+func CompileTargets(ctx context.Context, src io.Reader, s *schema.Schema, targets []string, output Output, opts ...Option) error {
+	return compile(ctx, src, s, targets, output, opts)
+}
+
+func compile(ctx context.Context, src io.Reader, s *schema.Schema, targets []string, output Output, opts []Option) error {
+	c := newConfig(opts)
+
+	resolved := make([]*schema.Target, len(targets))
+	writers := make([]*writer.Writer, len(targets))
+	for i, name := range targets {
+		target, err := s.GetTarget(name)
+		if err != nil && name != "" {
+			return c.reportError("", fmt.Errorf("%w: %s", err, name))
+		} else if err != nil {
+			return c.reportError("", err)
+		}
+
+		w, err := writer.New(target)
+		if err != nil {
+			return c.reportError("", err)
+		}
+		w.SetMaxBytes(c.limits.MaxOutputBytes)
+		if c.metadata != nil {
+			w.SetMetadata(c.metadata)
+		}
+
+		resolved[i] = target
+		writers[i] = w
+	}
+
+	document, err := Parse(ctx, src, s, opts...)
+	if err != nil {
+		return err
+	}
+
+	for _, filter := range c.filters {
+		err = ctx.Err()
+		if err != nil {
+			return err
+		}
+
+		document, err = filter(ctx, document)
+		if err != nil {
+			return c.reportError("", err)
+		}
+	}
+
+	n, err := numbering.Number(s, document)
+	if err != nil {
+		return c.reportError("", err)
+	}
+	o := outline.Collect(s, document, n)
+	citations := c.cite(s, document)
+
+	for i, w := range writers {
+		w.SetNumbering(n)
+		w.SetOutline(o)
+		w.SetCitations(citations)
+
+		err = writeTarget(ctx, w, output, resolved[i], document)
+		if err != nil {
+			return c.reportError("", err)
+		}
+	}
+
+	return nil
+}
+
+func writeTarget(ctx context.Context, w *writer.Writer, output Output, target *schema.Target, document *parser.Block) error {
+	dst, err := output(target)
+	if err != nil {
+		return err
+	}
+
+	err = w.WriteDocument(ctx, dst, document)
+
+	return errors.Join(err, dst.Close())
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// cite resolves citations, and reports undefined and unused keys
+func (c *config) cite(s *schema.Schema, document *parser.Block) *bibliography.Citations {
+	citations := bibliography.Cite(s, document, c.references)
+
+	for _, err := range citations.Undefined {
+		d := Diagnostic{Severity: SeverityWarning, Message: err.Error()}
+
+		var positionError *parser.Error
+		if errors.As(err, &positionError) {
+			d.Position = positionError.Position
+			d.Message = positionError.Err.Error()
+		}
+
+		c.report(d)
+	}
+
+	for _, key := range citations.Unused {
+		c.report(Diagnostic{Severity: SeverityWarning, Message: fmt.Sprintf("%s: %s", bibliography.ErrUnusedKey, key)})
+	}
+
+	return citations
+}
+
+// Parse parses the source document, resolves includes, and validates the
+// document with included content in place of include commands. Filters are
+// not applied.
+func Parse(ctx context.Context, src io.Reader, s *schema.Schema, opts ...Option) (*parser.Block, error) {
+	c := newConfig(opts)
+
+	document, err := parse(ctx, src, s, c.limits)
+	if err != nil {
+		return nil, c.reportError("", err)
+	}
+
+	inc := includer{config: &c, schema: s}
+
+	err = inc.expand(ctx, document)
+	if err != nil {
+		return nil, c.reportError("", err)
+	}
+
+	// included files are parts of the document, so content models apply to
+	// the document with includes expanded
+	err = s.Validate(document)
+	if err != nil {
+		return nil, c.reportError("", fmt.Errorf("parsing error: %w", err))
+	}
+
+	return document, nil
+}
+
+// commandValidator checks only names and arguments of commands, while the
+// document is validated once its includes are expanded
+type commandValidator struct {
+	schema *schema.Schema
+}
+
+func (v commandValidator) Validate(document parser.Element) error {
+	return nil
+}
+
+func (v commandValidator) ValidateSingleCommand(name string, args int) error {
+	return v.schema.ValidateSingleCommand(name, args)
+}
+
+func parse(ctx context.Context, src io.Reader, s *schema.Schema, limits Limits) (*parser.Block, error) {
+	tokenizer := parser.NewTokenizer(bufio.NewReader(src))
+	tokenizer.SetLimits(limits.parserLimits())
+
+	tokens, err := tokenizer.TokenizeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser.NewParser(tokens, commandValidator{schema: s})
+	p.SetLimits(limits.parserLimits())
+
+	return p.ParseContext(ctx)
+}
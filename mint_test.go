@@ -0,0 +1,487 @@
+package mint_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ubavic/mint"
+	"github.com/ubavic/mint/bibliography"
+	"github.com/ubavic/mint/parser"
+	"github.com/ubavic/mint/schema"
+	"gopkg.in/yaml.v3"
+)
+
+func testSchema() *schema.Schema {
+	return &schema.Schema{
+		Source: schema.Source{
+			Commands: []schema.Command{
+				{Command: "p", Arguments: 1},
+				{Command: "b", Arguments: 1},
+				{Command: "include", Arguments: 1, Include: true},
+			},
+		},
+		Targets: []schema.Target{
+			{
+				Name: "HTML",
+				Commands: []schema.TargetCommand{
+					{Command: "p", Expression: "<p>$1</p>"},
+					{Command: "b", Expression: "<b>$1</b>"},
+				},
+			},
+			{
+				Name: "Markdown",
+				Commands: []schema.TargetCommand{
+					{Command: "p", Expression: "$1\n\n"},
+					{Command: "b", Expression: "**$1**"},
+				},
+			},
+		},
+	}
+}
+
+func TestCompile(t *testing.T) {
+	files := fstest.MapFS{
+		"a.atex":     {Data: []byte("@p{A @include{b.atex}}")},
+		"b.atex":     {Data: []byte("@b{B}")},
+		"loop.atex":  {Data: []byte("@include{loop.atex}")},
+		"error.atex": {Data: []byte("@p{x}{y}")},
+	}
+
+	testCases := []struct {
+		input          string
+		options        []mint.Option
+		expectedResult string
+		expectedError  error
+	}{
+		{
+			input:          "@p{Hello @b{world}}",
+			expectedResult: "<p>Hello <b>world</b></p>",
+		},
+		{
+			input:          "@p{Hello @b{world}}",
+			options:        []mint.Option{mint.WithTarget("Markdown")},
+			expectedResult: "Hello **world**\n\n",
+		},
+		{
+			input:         "@p{Hello}",
+			options:       []mint.Option{mint.WithTarget("LaTeX")},
+			expectedError: schema.ErrTargetNotFound,
+		},
+		{
+			input:          "@include{a.atex}",
+			options:        []mint.Option{mint.WithIncludeResolver(mint.FSResolver(files))},
+			expectedResult: "<p>A <b>B</b></p>",
+		},
+		{
+			input:         "@include{a.atex}",
+			expectedError: mint.ErrNoIncludeResolver,
+		},
+		{
+			input:         "@include{loop.atex}",
+			options:       []mint.Option{mint.WithIncludeResolver(mint.FSResolver(files))},
+			expectedError: mint.ErrIncludeCycle,
+		},
+		{
+			input:         "@include{error.atex}",
+			options:       []mint.Option{mint.WithIncludeResolver(mint.FSResolver(files))},
+			expectedError: schema.ErrCommandInvalidArguments,
+		},
+		{
+			input: "@p{a}@p{b}",
+			options: []mint.Option{mint.WithFilters(func(ctx context.Context, document *parser.Block) (*parser.Block, error) {
+				document.Nodes = document.Nodes[1:]
+				return document, nil
+			})},
+			expectedResult: "<p>b</p>",
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(
+			fmt.Sprintf("TestCompile%d", i),
+			func(t *testing.T) {
+				var result strings.Builder
+
+				err := mint.Compile(context.Background(), &result, strings.NewReader(testCase.input), testSchema(), testCase.options...)
+
+				if testCase.expectedError != nil {
+					if !errors.Is(err, testCase.expectedError) {
+						t.Fatalf("Expected error \"%v\", got \"%v\"", testCase.expectedError, err)
+					}
+					return
+				}
+
+				if err != nil {
+					t.Fatalf("Expected no error, got \"%v\"", err)
+				}
+
+				if result.String() != testCase.expectedResult {
+					t.Errorf("Expected \"%s\", got \"%s\"", testCase.expectedResult, result.String())
+				}
+			},
+		)
+	}
+}
+
+func TestCompileDiagnostics(t *testing.T) {
+	files := fstest.MapFS{
+		"bad.atex": {Data: []byte("\n  @p{x}{y}")},
+	}
+
+	diagnostics := []mint.Diagnostic{}
+
+	err := mint.Compile(
+		context.Background(),
+		&strings.Builder{},
+		strings.NewReader("@p{a}\n@include{bad.atex}"),
+		testSchema(),
+		mint.WithIncludeResolver(mint.FSResolver(files)),
+		mint.WithDiagnostics(func(d mint.Diagnostic) {
+			diagnostics = append(diagnostics, d)
+		}),
+	)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected one diagnostic, got %v", diagnostics)
+	}
+
+	d := diagnostics[0]
+	if d.Severity != mint.SeverityError || d.File != "bad.atex" || d.Position != (parser.Position{Line: 2, Column: 3}) {
+		t.Errorf("Unexpected diagnostic %v", d)
+	}
+}
+
+func TestCompileValidatesIncludedContent(t *testing.T) {
+	s := &schema.Schema{
+		Source: schema.Source{
+			AllowedRootCommands: "root",
+			Commands: []schema.Command{
+				{Command: "title", Arguments: 1},
+				{Command: "section", Arguments: 1, AllowChildren: "body"},
+				{Command: "p", Arguments: 1},
+				{Command: "include", Arguments: 1, Include: true},
+			},
+			Groups: []schema.Group{
+				{Name: "root", Commands: []string{"title", "section", "include"}, Occurrences: []schema.Occurrence{{Command: "title", Min: 1, Max: 1}}},
+				{Name: "body", Commands: []string{"p", "include"}},
+			},
+		},
+		Targets: []schema.Target{
+			{
+				Name: "HTML",
+				Commands: []schema.TargetCommand{
+					{Command: "title", Expression: "<h1>$1</h1>"},
+					{Command: "section", Expression: "<section>$1</section>"},
+					{Command: "p", Expression: "<p>$1</p>"},
+				},
+			},
+		},
+	}
+
+	files := fstest.MapFS{
+		"part.atex":    {Data: []byte("@p{x}")},
+		"title.atex":   {Data: []byte("@title{T}")},
+		"section.atex": {Data: []byte("@section{}")},
+	}
+
+	testCases := []struct {
+		input          string
+		expectedResult string
+		expectedError  error
+	}{
+		{
+			input:          "@title{T}@section{@include{part.atex}}",
+			expectedResult: "<h1>T</h1><section><p>x</p></section>",
+		},
+		{
+			input:          "@include{title.atex}@section{}",
+			expectedResult: "<h1>T</h1><section></section>",
+		},
+		{
+			input:         "@title{T}@include{title.atex}",
+			expectedError: schema.ErrInvalidContent,
+		},
+		{
+			input:         "@title{T}@section{@include{section.atex}}",
+			expectedError: schema.ErrCommandNotAllowed,
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(fmt.Sprintf("TestCompileValidatesIncludedContent%d", i), func(t *testing.T) {
+			var result strings.Builder
+
+			err := mint.Compile(context.Background(), &result, strings.NewReader(testCase.input), s, mint.WithTarget("HTML"), mint.WithIncludeResolver(mint.FSResolver(files)))
+			if testCase.expectedError != nil {
+				if !errors.Is(err, testCase.expectedError) {
+					t.Fatalf("Expected error \"%v\", got \"%v\"", testCase.expectedError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error, got \"%v\"", err)
+			}
+
+			if result.String() != testCase.expectedResult {
+				t.Errorf("Expected \"%s\", got \"%s\"", testCase.expectedResult, result.String())
+			}
+		})
+	}
+}
+
+func TestCompileLimits(t *testing.T) {
+	files := fstest.MapFS{
+		"1.atex": {Data: []byte("@include{2.atex}")},
+		"2.atex": {Data: []byte("@include{3.atex}")},
+		"3.atex": {Data: []byte("@p{3}")},
+	}
+
+	testCases := []struct {
+		input         string
+		limits        mint.Limits
+		expectedError error
+	}{
+		{
+			input:  "@include{1.atex}",
+			limits: mint.Limits{MaxIncludeDepth: 3},
+		},
+		{
+			input:         "@include{1.atex}",
+			limits:        mint.Limits{MaxIncludeDepth: 2},
+			expectedError: parser.ErrLimitExceeded,
+		},
+		{
+			input:  "@p{abc}",
+			limits: mint.Limits{MaxOutputBytes: 10},
+		},
+		{
+			input:         "@p{abcd}",
+			limits:        mint.Limits{MaxOutputBytes: 10},
+			expectedError: parser.ErrLimitExceeded,
+		},
+		{
+			input:         "@p{@b{@b{x}}}",
+			limits:        mint.Limits{MaxDepth: 2},
+			expectedError: parser.ErrLimitExceeded,
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(
+			fmt.Sprintf("TestCompileLimits%d", i),
+			func(t *testing.T) {
+				err := mint.Compile(
+					context.Background(),
+					&strings.Builder{},
+					strings.NewReader(testCase.input),
+					testSchema(),
+					mint.WithIncludeResolver(mint.FSResolver(files)),
+					mint.WithLimits(testCase.limits),
+				)
+
+				if !errors.Is(err, testCase.expectedError) {
+					t.Fatalf("Expected error \"%v\", got \"%v\"", testCase.expectedError, err)
+				}
+			},
+		)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := mint.Compile(ctx, &strings.Builder{}, strings.NewReader("@p{a}"), testSchema())
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got \"%v\"", err)
+	}
+}
+
+func TestCompileNumbering(t *testing.T) {
+	s := &schema.Schema{
+		Source: schema.Source{
+			Counters: []schema.Counter{
+				{Name: "section"},
+				{Name: "subsection", Within: "section"},
+			},
+			Commands: []schema.Command{
+				{Command: "section", Arguments: 2, Counter: "section", Label: 2},
+				{Command: "subsection", Arguments: 1, Counter: "subsection"},
+				{Command: "ref", Arguments: 1},
+			},
+		},
+		Targets: []schema.Target{
+			{
+				Commands: []schema.TargetCommand{
+					{Command: "section", Template: "<h2>{{.Number}}. {{.Arg 1}}</h2>"},
+					{Command: "subsection", Expression: "<h3>${#}. $1</h3>"},
+					{Command: "ref", Template: "see section {{.Ref (.Text 1)}}"},
+				},
+			},
+		},
+	}
+
+	var result strings.Builder
+
+	err := mint.Compile(context.Background(), &result, strings.NewReader("@section{A}{a}@ref{b}@section{B}{b}@subsection{C}"), s)
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	expected := "<h2>1. A</h2>see section 2<h2>2. B</h2><h3>2.1. C</h3>"
+	if result.String() != expected {
+		t.Errorf("Expected \"%s\", got \"%s\"", expected, result.String())
+	}
+}
+
+func TestCompileBibliography(t *testing.T) {
+	s := &schema.Schema{
+		Source: schema.Source{
+			BibliographyCommand: "references",
+			Commands: []schema.Command{
+				{Command: "cite", Arguments: 1, Cite: 1},
+				{Command: "references"},
+			},
+		},
+		Targets: []schema.Target{
+			{
+				Bibliography: `<ol>{{range .References}}<li id="{{.Key}}">{{.Fields.author}}: {{.Fields.title}}</li>{{end}}</ol>`,
+				Commands: []schema.TargetCommand{
+					{Command: "cite", Template: `[{{range $i, $c := .Citations}}{{if $i}}, {{end}}{{if $c.Number}}{{$c.Number}}{{else}}?{{end}}{{end}}]`},
+					{Command: "references"},
+				},
+			},
+		},
+	}
+
+	b, err := bibliography.Load("references.bib", []byte(`
+@book{knuth84, author = {Knuth}, title = {The TeXbook}}
+@book{lamport94, author = {Lamport}, title = {LaTeX}}
+@book{unused, author = {Nobody}, title = {Nothing}}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	diagnostics := []string{}
+	sink := func(d mint.Diagnostic) {
+		diagnostics = append(diagnostics, d.String())
+	}
+
+	var result strings.Builder
+
+	err = mint.Compile(context.Background(), &result, strings.NewReader("@cite{lamport94}@cite{knuth84,lamport94}\n@cite{missing}@references"), s, mint.WithBibliography(b), mint.WithDiagnostics(sink))
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	expected := `[1][2, 1][?]<ol><li id="lamport94">Lamport: LaTeX</li><li id="knuth84">Knuth: The TeXbook</li></ol>`
+	if result.String() != expected {
+		t.Errorf("Expected \"%s\", got \"%s\"", expected, result.String())
+	}
+
+	expectedDiagnostics := []string{
+		"2:1: warning: undefined bibliography key: missing",
+		"-: warning: unused bibliography key: unused",
+	}
+	if strings.Join(diagnostics, "\n") != strings.Join(expectedDiagnostics, "\n") {
+		t.Errorf("Expected diagnostics %v, got %v", expectedDiagnostics, diagnostics)
+	}
+}
+
+type closeRecorder struct {
+	strings.Builder
+	closed bool
+}
+
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestCompileTargets(t *testing.T) {
+	outputs := map[string]*closeRecorder{}
+	output := func(target *schema.Target) (io.WriteCloser, error) {
+		outputs[target.Name] = &closeRecorder{}
+		return outputs[target.Name], nil
+	}
+
+	err := mint.CompileTargets(context.Background(), strings.NewReader("@p{Hello @b{world}}"), testSchema(), []string{"Markdown", "HTML"}, output)
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	expected := map[string]string{
+		"HTML":     "<p>Hello <b>world</b></p>",
+		"Markdown": "Hello **world**\n\n",
+	}
+
+	for name, result := range expected {
+		if outputs[name] == nil || outputs[name].String() != result || !outputs[name].closed {
+			t.Errorf("Expected closed output \"%s\" for %s, got %v", result, name, outputs[name])
+		}
+	}
+
+	err = mint.CompileTargets(context.Background(), strings.NewReader("@p{Hello}"), testSchema(), []string{"HTML", "LaTeX"}, output)
+	if !errors.Is(err, schema.ErrTargetNotFound) {
+		t.Errorf("Expected error \"%v\", got \"%v\"", schema.ErrTargetNotFound, err)
+	}
+}
+
+func BenchmarkCompile(b *testing.B) {
+	built := &schema.Schema{Targets: []schema.Target{{Name: "HTML"}}}
+
+	for i := range 400 {
+		name := fmt.Sprintf("c%d", i)
+		built.Source.Commands = append(built.Source.Commands, schema.Command{Command: name, Arguments: 1})
+		built.Targets[0].Commands = append(built.Targets[0].Commands, schema.TargetCommand{Command: name, Expression: "<" + name + ">$1</" + name + ">"})
+	}
+
+	encoded, err := yaml.Marshal(built)
+	if err != nil {
+		b.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	loaded, err := schema.Load(encoded)
+	if err != nil {
+		b.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	compiled := *built
+	err = compiled.Compile()
+	if err != nil {
+		b.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	var document strings.Builder
+	for i := range 25000 {
+		fmt.Fprintf(&document, "@c%d{text @c%d{a} and @c%d{b}}\n", i%200, 200+i%200, 399-i%200)
+	}
+	source := document.String()
+
+	benchmarks := []struct {
+		name   string
+		schema *schema.Schema
+	}{
+		{name: "Built", schema: built},
+		{name: "Compiled", schema: &compiled},
+		{name: "Loaded", schema: loaded},
+	}
+
+	for _, benchmark := range benchmarks {
+		b.Run(benchmark.name, func(b *testing.B) {
+			for range b.N {
+				err := mint.Compile(context.Background(), io.Discard, strings.NewReader(source), benchmark.schema, mint.WithTarget("HTML"))
+				if err != nil {
+					b.Fatalf("Expected no error, got \"%v\"", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+// Package numbering assigns numbers to commands that increment schema
+// counters, and collects labels for cross-references. It runs over the whole
+// document before writing, so references to later commands are resolved.
+package numbering
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ubavic/mint/parser"
+	"github.com/ubavic/mint/schema"
+)
+
+var ErrDuplicateLabel = errors.New("duplicate label")
+var ErrUndefinedReference = errors.New("undefined reference")
+
+// Value is a number assigned to a command
+type Value struct {
+	Counter string
+	Value   int
+	// Number includes numbers of enclosing counters, like "3.2"
+	Number string
+}
+
+// state holds values of all counters at some point in the document. States
+// are never modified, so commands between two increments share one state.
+type state map[string]int
+
+type Numbering struct {
+	schema   *schema.Schema
+	values   map[*parser.Command]Value
+	states   map[*parser.Command]state
+	labels   map[string]Value
+	children map[string][]string
+}
+
+// Number walks the document in order, and increments counters of commands
+func Number(s *schema.Schema, document parser.Element) (*Numbering, error) {
+	n := &Numbering{
+		schema:   s,
+		values:   map[*parser.Command]Value{},
+		states:   map[*parser.Command]state{},
+		labels:   map[string]Value{},
+		children: map[string][]string{},
+	}
+
+	for _, counter := range s.Source.Counters {
+		if counter.Within != "" {
+			n.children[counter.Within] = append(n.children[counter.Within], counter.Name)
+		}
+	}
+
+	current := state{}
+	err := n.walk(document, &current)
+	if err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+func (n *Numbering) walk(element parser.Element, current *state) error {
+	command, ok := element.(*parser.Command)
+	if !ok {
+		for _, node := range element.Content() {
+			err := n.walk(node, current)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	definition, err := n.schema.GetCommand(command.Name)
+	if err == nil && definition.Counter != "" {
+		*current = n.increment(*current, definition.Counter)
+
+		value := Value{
+			Counter: definition.Counter,
+			Value:   (*current)[definition.Counter],
+			Number:  n.number(*current, definition.Counter),
+		}
+		n.values[command] = value
+
+		if definition.Label > 0 && definition.Label <= len(command.Arguments) {
+			label := strings.TrimSpace(parser.PlainText(command.Arguments[definition.Label-1]))
+			if _, ok := n.labels[label]; ok {
+				return &parser.Error{Position: command.Position, Err: fmt.Errorf("%w: %s", ErrDuplicateLabel, label)}
+			}
+			n.labels[label] = value
+		}
+	}
+
+	n.states[command] = *current
+
+	for _, arg := range command.Arguments {
+		err := n.walk(arg, current)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// increment returns a new state with the counter incremented, and counters
+// within it reset
+func (n *Numbering) increment(current state, counter string) state {
+	next := make(state, len(current)+1)
+	for name, value := range current {
+		next[name] = value
+	}
+
+	next[counter] += 1
+	n.reset(next, counter)
+
+	return next
+}
+
+func (n *Numbering) reset(s state, counter string) {
+	for _, child := range n.children[counter] {
+		delete(s, child)
+		n.reset(s, child)
+	}
+}
+
+func (n *Numbering) number(s state, counter string) string {
+	number := strconv.Itoa(s[counter])
+
+	definition, err := n.schema.GetCounter(counter)
+	if err == nil && definition.Within != "" {
+		return n.number(s, definition.Within) + "." + number
+	}
+
+	return number
+}
+
+// Of returns the value assigned to the command
+func (n *Numbering) Of(command *parser.Command) (Value, bool) {
+	value, ok := n.values[command]
+	return value, ok
+}
+
+// Counter returns the value of the counter at the command
+func (n *Numbering) Counter(command *parser.Command, counter string) int {
+	return n.states[command][counter]
+}
+
+// Ref returns the value of the labeled command
+func (n *Numbering) Ref(label string) (Value, error) {
+	value, ok := n.labels[label]
+	if !ok {
+		return Value{}, fmt.Errorf("%w: %s", ErrUndefinedReference, label)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,98 @@
+package numbering_test
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ubavic/mint/numbering"
+	"github.com/ubavic/mint/parser"
+	"github.com/ubavic/mint/query"
+	"github.com/ubavic/mint/schema"
+)
+
+var testSchema = &schema.Schema{
+	Source: schema.Source{
+		Counters: []schema.Counter{
+			{Name: "section"},
+			{Name: "subsection", Within: "section"},
+			{Name: "subsubsection", Within: "subsection"},
+			{Name: "figure"},
+		},
+		Commands: []schema.Command{
+			{Command: "section", Arguments: 2, Counter: "section", Label: 2},
+			{Command: "subsection", Arguments: 1, Counter: "subsection"},
+			{Command: "subsubsection", Arguments: 1, Counter: "subsubsection"},
+			{Command: "figure", Arguments: 2, Counter: "figure", Label: 2},
+			{Command: "ref", Arguments: 1},
+		},
+	},
+}
+
+func parse(t *testing.T, input string) *parser.Block {
+	tokenizer := parser.NewTokenizer(bufio.NewReader(strings.NewReader(input)))
+	p := parser.NewParser(tokenizer.Tokenize(), testSchema)
+
+	block, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%s\"", err)
+	}
+
+	return block
+}
+
+func TestNumber(t *testing.T) {
+	document := parse(t, `
+@section{A}{a}
+@subsection{A.1}
+@subsubsection{A.1.1}
+@figure{F}{f1}
+@subsection{A.2}
+@subsubsection{A.2.1}
+@ref{b}
+@section{B}{b}
+@subsubsection{B.0.1}
+@subsection{B.1}
+@figure{G}{f2}`)
+
+	n, err := numbering.Number(testSchema, document)
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	numbers := []string{}
+	for _, match := range query.MustCompile("*").Find(document) {
+		value, _ := n.Of(match.Command)
+		numbers = append(numbers, value.Number)
+	}
+
+	expected := "1 1.1 1.1.1 1 1.2 1.2.1  2 2.0.1 2.1 2"
+	if strings.Join(numbers, " ") != expected {
+		t.Errorf("Expected numbers \"%s\", got \"%s\"", expected, strings.Join(numbers, " "))
+	}
+
+	ref := query.MustCompile("ref").Find(document)[0].Command
+	if n.Counter(ref, "section") != 1 || n.Counter(ref, "subsection") != 2 || n.Counter(ref, "figure") != 1 {
+		t.Errorf("Unexpected counter values at @ref")
+	}
+
+	for label, expected := range map[string]string{"a": "1", "b": "2", "f2": "2"} {
+		value, err := n.Ref(label)
+		if err != nil || value.Number != expected {
+			t.Errorf("Expected label %s to have number %s, got %v (%v)", label, expected, value.Number, err)
+		}
+	}
+
+	_, err = n.Ref("missing")
+	if !errors.Is(err, numbering.ErrUndefinedReference) {
+		t.Errorf("Expected error \"%v\", got \"%v\"", numbering.ErrUndefinedReference, err)
+	}
+}
+
+func TestNumberDuplicateLabel(t *testing.T) {
+	_, err := numbering.Number(testSchema, parse(t, "@section{A}{a}@figure{F}{a}"))
+	if !errors.Is(err, numbering.ErrDuplicateLabel) {
+		t.Errorf("Expected error \"%v\", got \"%v\"", numbering.ErrDuplicateLabel, err)
+	}
+}
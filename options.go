@@ -0,0 +1,113 @@
+package mint
+
+import (
+	"context"
+
+	"github.com/ubavic/mint/bibliography"
+	"github.com/ubavic/mint/parser"
+)
+
+// Filter transforms a parsed document before it is written. Filters are
+// applied in the order they are given.
+type Filter func(ctx context.Context, document *parser.Block) (*parser.Block, error)
+
+type Option func(*config)
+
+type config struct {
+	target      string
+	filters     []Filter
+	resolver    IncludeResolver
+	diagnostics DiagnosticSink
+	limits      Limits
+	metadata    map[string]string
+	references  *bibliography.Bibliography
+}
+
+func newConfig(opts []Option) config {
+	c := config{}
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return c
+}
+
+// WithTarget selects a target from the schema by name. By default, the first
+// target is used.
+func WithTarget(name string) Option {
+	return func(c *config) {
+		c.target = name
+	}
+}
+
+// WithFilters appends filters applied to the document after includes are
+// resolved
+func WithFilters(filters ...Filter) Option {
+	return func(c *config) {
+		c.filters = append(c.filters, filters...)
+	}
+}
+
+// WithIncludeResolver sets a resolver used for commands marked with
+// `include` in the schema. Without a resolver, such commands are reported as
+// errors.
+func WithIncludeResolver(resolver IncludeResolver) Option {
+	return func(c *config) {
+		c.resolver = resolver
+	}
+}
+
+// WithMetadata sets document metadata available to target templates as
+// `.Meta`
+func WithMetadata(metadata map[string]string) Option {
+	return func(c *config) {
+		c.metadata = metadata
+	}
+}
+
+// WithBibliography sets entries cited by commands with a `cite` argument.
+// Undefined and unused keys are reported as warnings.
+func WithBibliography(b *bibliography.Bibliography) Option {
+	return func(c *config) {
+		c.references = b
+	}
+}
+
+// WithDiagnostics sets a sink that receives diagnostics
+func WithDiagnostics(sink DiagnosticSink) Option {
+	return func(c *config) {
+		c.diagnostics = sink
+	}
+}
+
+// Limits bound resources used for a single compilation, and should be set
+// when input is untrusted. Zero value of a field means no limit. Exceeding a
+// limit returns an error that matches parser.ErrLimitExceeded.
+type Limits struct {
+	// MaxDepth is the maximal nesting of command arguments
+	MaxDepth int
+	// MaxTokens is the maximal number of tokens in a single file
+	MaxTokens int
+	// MaxArguments is the maximal number of arguments of a single command
+	MaxArguments int
+	// MaxIncludeDepth is the maximal nesting of included files
+	MaxIncludeDepth int
+	// MaxOutputBytes is the maximal size of the rendered document
+	MaxOutputBytes int
+}
+
+// WithLimits sets resource limits
+func WithLimits(limits Limits) Option {
+	return func(c *config) {
+		c.limits = limits
+	}
+}
+
+func (l Limits) parserLimits() parser.Limits {
+	return parser.Limits{
+		MaxDepth:     l.MaxDepth,
+		MaxTokens:    l.MaxTokens,
+		MaxArguments: l.MaxArguments,
+	}
+}
@@ -0,0 +1,132 @@
+// Package outline collects commands marked as headings in the schema into a
+// tree. Titles are rendered later by the writer, before the document itself,
+// so the table of contents can precede headings.
+package outline
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ubavic/mint/numbering"
+	"github.com/ubavic/mint/parser"
+	"github.com/ubavic/mint/schema"
+)
+
+type Outline struct {
+	// TocCommand is replaced with the table of contents
+	TocCommand string
+	Entries    []*schema.OutlineEntry
+
+	titles   map[*schema.OutlineEntry]parser.Element
+	commands map[*parser.Command]*schema.OutlineEntry
+}
+
+// Collect walks the document in order, and builds the outline. Numbers of
+// headings are taken from n, which may be nil.
+func Collect(s *schema.Schema, document parser.Element, n *numbering.Numbering) *Outline {
+	c := collector{
+		schema:    s,
+		numbering: n,
+		outline: &Outline{
+			TocCommand: s.Source.TocCommand,
+			Entries:    []*schema.OutlineEntry{},
+			titles:     map[*schema.OutlineEntry]parser.Element{},
+			commands:   map[*parser.Command]*schema.OutlineEntry{},
+		},
+		anchors: map[string]bool{},
+	}
+
+	c.walk(document)
+
+	return c.outline
+}
+
+// Entry returns the outline entry of a heading command
+func (o *Outline) Entry(command *parser.Command) (*schema.OutlineEntry, bool) {
+	entry, ok := o.commands[command]
+	return entry, ok
+}
+
+// Title returns the title argument of the entry
+func (o *Outline) Title(entry *schema.OutlineEntry) parser.Element {
+	return o.titles[entry]
+}
+
+type collector struct {
+	schema    *schema.Schema
+	numbering *numbering.Numbering
+	outline   *Outline
+	// stack holds the last entry of each open level
+	stack   []*schema.OutlineEntry
+	anchors map[string]bool
+}
+
+func (c *collector) walk(element parser.Element) {
+	command, ok := element.(*parser.Command)
+	if !ok {
+		for _, node := range element.Content() {
+			c.walk(node)
+		}
+		return
+	}
+
+	definition, err := c.schema.GetCommand(command.Name)
+	if err == nil && definition.Heading != nil && definition.Heading.Title <= len(command.Arguments) {
+		c.add(command, definition.Heading)
+	}
+
+	for _, arg := range command.Arguments {
+		c.walk(arg)
+	}
+}
+
+func (c *collector) add(command *parser.Command, heading *schema.Heading) {
+	title := command.Arguments[heading.Title-1]
+	text := strings.TrimSpace(parser.PlainText(title))
+
+	entry := &schema.OutlineEntry{
+		Level:    heading.Level,
+		Command:  command.Name,
+		Text:     text,
+		Anchor:   c.anchor(text),
+		Children: []*schema.OutlineEntry{},
+	}
+
+	if c.numbering != nil {
+		value, _ := c.numbering.Of(command)
+		entry.Number = value.Number
+	}
+
+	c.outline.titles[entry] = title
+	c.outline.commands[command] = entry
+
+	for len(c.stack) > 0 && c.stack[len(c.stack)-1].Level >= entry.Level {
+		c.stack = c.stack[:len(c.stack)-1]
+	}
+
+	if len(c.stack) == 0 {
+		c.outline.Entries = append(c.outline.Entries, entry)
+	} else {
+		parent := c.stack[len(c.stack)-1]
+		parent.Children = append(parent.Children, entry)
+	}
+
+	c.stack = append(c.stack, entry)
+}
+
+// anchor returns a slug of the text that is unique in the document
+func (c *collector) anchor(text string) string {
+	base := schema.Slug(text)
+	if base == "" {
+		base = "section"
+	}
+
+	anchor := base
+	for i := 2; c.anchors[anchor]; i++ {
+		anchor = base + "-" + strconv.Itoa(i)
+	}
+
+	c.anchors[anchor] = true
+
+	return anchor
+}
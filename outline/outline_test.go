@@ -0,0 +1,139 @@
+package outline_test
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ubavic/mint/numbering"
+	"github.com/ubavic/mint/outline"
+	"github.com/ubavic/mint/parser"
+	"github.com/ubavic/mint/schema"
+)
+
+var testSchema = &schema.Schema{
+	Source: schema.Source{
+		TocCommand: "toc",
+		Counters: []schema.Counter{
+			{Name: "section"},
+			{Name: "subsection", Within: "section"},
+		},
+		Commands: []schema.Command{
+			{Command: "toc"},
+			{Command: "chapter", Arguments: 1, Heading: &schema.Heading{Level: 1, Title: 1}},
+			{Command: "section", Arguments: 2, Counter: "section", Heading: &schema.Heading{Level: 2, Title: 2}},
+			{Command: "subsection", Arguments: 1, Counter: "subsection", Heading: &schema.Heading{Level: 3, Title: 1}},
+			{Command: "b", Arguments: 1},
+		},
+	},
+}
+
+func parse(t *testing.T, input string) *parser.Block {
+	tokenizer := parser.NewTokenizer(bufio.NewReader(strings.NewReader(input)))
+	p := parser.NewParser(tokenizer.Tokenize(), testSchema)
+
+	block, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%s\"", err)
+	}
+
+	return block
+}
+
+// format lists entries as "level number anchor text", indented by depth
+func format(entries []*schema.OutlineEntry, depth int) string {
+	result := ""
+
+	for _, entry := range entries {
+		result += fmt.Sprintf("%s%d %s %s %s\n", strings.Repeat(" ", depth), entry.Level, entry.Number, entry.Anchor, entry.Text)
+		result += format(entry.Children, depth+1)
+	}
+
+	return result
+}
+
+func TestCollect(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{
+			input:    `@toc text`,
+			expected: ``,
+		},
+		{
+			input: `@toc
+@section{x}{Intro}
+@subsection{First @b{part}}
+@subsection{Second}
+@section{y}{Intro}`,
+			expected: `2 1 intro Intro
+ 3 1.1 first-part First part
+ 3 1.2 second Second
+2 2 intro-2 Intro
+`,
+		},
+		{
+			input: `@subsection{Orphan}
+@chapter{One}
+@section{x}{A}
+@chapter{Two}
+@subsection{B}
+@section{y}{C}`,
+			expected: `3 0.1 orphan Orphan
+1  one One
+ 2 1 a A
+1  two Two
+ 3 1.1 b B
+ 2 2 c C
+`,
+		},
+		{
+			input:    `@chapter{@b{}}`,
+			expected: "1  section \n",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TestCollect%d", i), func(t *testing.T) {
+			document := parse(t, tc.input)
+
+			n, err := numbering.Number(testSchema, document)
+			if err != nil {
+				t.Fatalf("Expected no error, got \"%s\"", err)
+			}
+
+			o := outline.Collect(testSchema, document, n)
+
+			if o.TocCommand != "toc" {
+				t.Errorf("Expected toc command \"toc\", got \"%s\"", o.TocCommand)
+			}
+
+			result := format(o.Entries, 0)
+			if result != tc.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestEntry(t *testing.T) {
+	document := parse(t, `@chapter{One}@b{x}`)
+	o := outline.Collect(testSchema, document, nil)
+
+	chapter := document.Content()[0].(*parser.Command)
+	entry, ok := o.Entry(chapter)
+	if !ok || entry.Anchor != "one" {
+		t.Errorf("Expected entry with anchor \"one\", got %v", entry)
+	}
+
+	if title := parser.PlainText(o.Title(entry)); title != "One" {
+		t.Errorf("Expected title \"One\", got \"%s\"", title)
+	}
+
+	b := document.Content()[1].(*parser.Command)
+	if _, ok := o.Entry(b); ok {
+		t.Errorf("Expected no entry for @b")
+	}
+}
@@ -11,7 +11,8 @@ type Element interface {
 }
 
 type Block struct {
-	Nodes []Element
+	Nodes    []Element
+	Position Position
 }
 
 func (doc Block) Content() []Element {
@@ -40,6 +41,7 @@ func (block Block) Json() []byte {
 type Command struct {
 	Name      string
 	Arguments []Element
+	Position  Position
 }
 
 func (com Command) Content() []Element {
@@ -58,6 +60,7 @@ func (cmd Command) String() string {
 
 type TextContent struct {
 	TextContent string
+	Position    Position
 }
 
 func (tc TextContent) Content() []Element {
@@ -3,3 +3,17 @@ package parser
 import "errors"
 
 var ErrUnexpectedToken = errors.New("unexpected token")
+
+// Error is an error at a known position in the source
+type Error struct {
+	Position Position
+	Err      error
+}
+
+func (e *Error) Error() string {
+	return e.Position.String() + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
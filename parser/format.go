@@ -0,0 +1,51 @@
+package parser
+
+import "strings"
+
+// Format renders element back to atex source. Special characters in text are
+// escaped, so the result can be tokenized again.
+func Format(element Element) string {
+	var builder strings.Builder
+	format(&builder, element)
+	return builder.String()
+}
+
+func format(builder *strings.Builder, element Element) {
+	switch v := element.(type) {
+	case *TextContent:
+		builder.WriteString(escapeText(v.TextContent))
+	case *Block:
+		for _, node := range v.Nodes {
+			format(builder, node)
+		}
+	case *Command:
+		builder.WriteString("@" + v.Name)
+		for _, arg := range v.Arguments {
+			builder.WriteString("{")
+			format(builder, arg)
+			builder.WriteString("}")
+		}
+	default:
+		builder.WriteString(element.String())
+	}
+}
+
+var textEscaper = strings.NewReplacer("@", "@@", "{", "@{", "}", "@}")
+
+func escapeText(text string) string {
+	return textEscaper.Replace(text)
+}
+
+// PlainText concatenates text of the element and all its descendants
+func PlainText(element Element) string {
+	if text, ok := element.(*TextContent); ok {
+		return text.TextContent
+	}
+
+	var builder strings.Builder
+	for _, node := range element.Content() {
+		builder.WriteString(PlainText(node))
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,38 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// Limits bound resources used while processing untrusted input. Zero value
+// of a field means no limit.
+type Limits struct {
+	// MaxDepth is the maximal nesting of command arguments
+	MaxDepth int
+	// MaxTokens is the maximal number of tokens in a document
+	MaxTokens int
+	// MaxArguments is the maximal number of arguments of a single command
+	MaxArguments int
+}
+
+// LimitError is returned when input exceeds one of the limits. It matches
+// ErrLimitExceeded with errors.Is.
+type LimitError struct {
+	Limit string
+	Max   int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s: %s is limited to %d", ErrLimitExceeded, e.Limit, e.Max)
+}
+
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// checkInterval is the number of iterations of the tokenizer and the parser
+// between two checks of the context
+const checkInterval = 1024
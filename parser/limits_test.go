@@ -0,0 +1,136 @@
+package parser_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ubavic/mint/parser"
+)
+
+func TestLimits(t *testing.T) {
+	testCases := []struct {
+		input         string
+		limits        parser.Limits
+		expectedError error
+	}{
+		{
+			input:  "@a{@a{@a{}}}",
+			limits: parser.Limits{MaxDepth: 3},
+		},
+		{
+			input:         "@a{@a{@a{@a{}}}}",
+			limits:        parser.Limits{MaxDepth: 3},
+			expectedError: parser.ErrLimitExceeded,
+		},
+		{
+			input:  "@a{}{}{}",
+			limits: parser.Limits{MaxArguments: 3},
+		},
+		{
+			input:         "@a{}{}{}{}",
+			limits:        parser.Limits{MaxArguments: 3},
+			expectedError: parser.ErrLimitExceeded,
+		},
+		{
+			input:  "@a{b}",
+			limits: parser.Limits{MaxTokens: 5},
+		},
+		{
+			input:         "@a{b} @c",
+			limits:        parser.Limits{MaxTokens: 5},
+			expectedError: parser.ErrLimitExceeded,
+		},
+		{
+			input: strings.Repeat("@a{", 10000) + strings.Repeat("}", 10000),
+		},
+		{
+			input:         strings.Repeat("@a{", 10000) + strings.Repeat("}", 10000),
+			limits:        parser.Limits{MaxDepth: 100},
+			expectedError: parser.ErrLimitExceeded,
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(
+			fmt.Sprintf("TestLimits%d", i),
+			func(t *testing.T) {
+				tokenizer := parser.NewTokenizer(bufio.NewReader(strings.NewReader(testCase.input)))
+				tokenizer.SetLimits(testCase.limits)
+
+				tokens, err := tokenizer.TokenizeContext(context.Background())
+				if err == nil {
+					p := parser.NewParser(tokens, &parser.OptimisticValidator{})
+					p.SetLimits(testCase.limits)
+					_, err = p.ParseContext(context.Background())
+				}
+
+				if !errors.Is(err, testCase.expectedError) {
+					t.Fatalf("Expected error \"%v\", got \"%v\"", testCase.expectedError, err)
+				}
+
+				var limitError *parser.LimitError
+				if testCase.expectedError != nil && !errors.As(err, &limitError) {
+					t.Errorf("Expected LimitError, got %T", err)
+				}
+			},
+		)
+	}
+}
+
+func TestCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tokenizer := parser.NewTokenizer(bufio.NewReader(strings.NewReader("@p{a}")))
+	_, err := tokenizer.TokenizeContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got \"%v\"", err)
+	}
+
+	p := parser.NewParser([]parser.Token{{Type: parser.Identifier, Content: "p"}}, &parser.OptimisticValidator{})
+	_, err = p.ParseContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got \"%v\"", err)
+	}
+}
+
+// cancelAfter is a context canceled after the given number of checks
+type cancelAfter struct {
+	context.Context
+	checks int
+}
+
+func (c *cancelAfter) Err() error {
+	c.checks--
+	if c.checks < 0 {
+		return context.Canceled
+	}
+
+	return nil
+}
+
+func TestCanceledDuringParsing(t *testing.T) {
+	// tokens are appended two at a time after the first one, and commands
+	// advance the parser by several tokens, so counts of both skip multiples
+	// of the check interval
+	input := "{" + strings.Repeat(" x@b", 5000)
+
+	tokenizer := parser.NewTokenizer(bufio.NewReader(strings.NewReader(input)))
+	_, err := tokenizer.TokenizeContext(&cancelAfter{Context: context.Background(), checks: 1})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got \"%v\"", err)
+	}
+
+	tokenizer = parser.NewTokenizer(bufio.NewReader(strings.NewReader("x" + strings.Repeat("@a{} ", 5000))))
+	tokens := tokenizer.Tokenize()
+
+	p := parser.NewParser(tokens, &parser.OptimisticValidator{})
+	_, err = p.ParseContext(&cancelAfter{Context: context.Background(), checks: 1})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got \"%v\"", err)
+	}
+}
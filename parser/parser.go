@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"context"
 	"errors"
 	"fmt"
 )
@@ -9,6 +10,11 @@ type Parser struct {
 	tokens          []Token
 	currentPosition int
 	validator       Validator
+	limits          Limits
+	depth           int
+	ctx             context.Context
+	// untilCheck counts iterations down to the next check of the context
+	untilCheck int
 }
 
 func NewParser(tokens []Token, validator Validator) Parser {
@@ -20,19 +26,32 @@ func NewParser(tokens []Token, validator Validator) Parser {
 		tokens:          tokens,
 		currentPosition: 0,
 		validator:       validator,
+		ctx:             context.Background(),
 	}
 
 	return parser
 }
 
+func (p *Parser) SetLimits(limits Limits) {
+	p.limits = limits
+}
+
 func (p *Parser) Parse() (*Block, error) {
+	return p.ParseContext(context.Background())
+}
+
+// ParseContext parses tokens, and stops when the context is canceled
+func (p *Parser) ParseContext(ctx context.Context) (*Block, error) {
+	p.ctx = ctx
+	p.untilCheck = 0
+
 	document, err := p.parseBlock()
 	if err != nil {
 		return nil, err
 	}
 
 	if p.currentToken().Type != EOF {
-		return nil, errors.New("didn't reach end of file")
+		return nil, &Error{Position: p.currentToken().Position, Err: errors.New("didn't reach end of file")}
 	}
 
 	err = p.validator.Validate(document)
@@ -51,12 +70,23 @@ func (p *Parser) parseBlock() (*Block, error) {
 	for {
 		currentToken := p.currentToken()
 
+		if p.untilCheck == 0 {
+			err := p.ctx.Err()
+			if err != nil {
+				return nil, err
+			}
+
+			p.untilCheck = checkInterval
+		}
+		p.untilCheck--
+
 		switch currentToken.Type {
 		case EOF:
 			return &block, nil
 		case Identifier:
 			command := Command{
-				Name: currentToken.Content,
+				Name:     currentToken.Content,
+				Position: currentToken.Position,
 			}
 
 			args, err := p.parseArguments()
@@ -66,16 +96,16 @@ func (p *Parser) parseBlock() (*Block, error) {
 
 			err = p.validator.ValidateSingleCommand(command.Name, len(args))
 			if err != nil {
-				return nil, err
+				return nil, &Error{Position: command.Position, Err: err}
 			}
 
 			command.Arguments = args
 			block.Nodes = append(block.Nodes, &command)
 		case Text:
-			tc := TextContent{TextContent: currentToken.Content}
+			tc := TextContent{TextContent: currentToken.Content, Position: currentToken.Position}
 			block.Nodes = append(block.Nodes, &tc)
 		case LeftBrace:
-			return nil, fmt.Errorf("%w %s", ErrUnexpectedToken, currentToken.String())
+			return nil, &Error{Position: currentToken.Position, Err: fmt.Errorf("%w %s", ErrUnexpectedToken, currentToken.String())}
 		case RightBrace:
 			return &block, nil
 		}
@@ -94,6 +124,10 @@ func (p *Parser) parseArguments() ([]Element, error) {
 
 		switch currentToken.Type {
 		case LeftBrace:
+			if p.limits.MaxArguments > 0 && len(arguments) >= p.limits.MaxArguments {
+				return nil, &Error{Position: currentToken.Position, Err: &LimitError{Limit: "argument count", Max: p.limits.MaxArguments}}
+			}
+
 			element, err := p.parseArgument()
 			if err != nil {
 				return nil, err
@@ -122,21 +156,32 @@ func (p *Parser) parseArguments() ([]Element, error) {
 }
 
 func (p *Parser) parseArgument() (Element, error) {
+	position := p.currentToken().Position
+
 	err := p.parseToken(LeftBrace)
 	if err != nil {
 		return nil, err
 	}
 
+	p.depth += 1
+	if p.limits.MaxDepth > 0 && p.depth > p.limits.MaxDepth {
+		return nil, &Error{Position: position, Err: &LimitError{Limit: "nesting depth", Max: p.limits.MaxDepth}}
+	}
+
 	el, err := p.parseBlock()
 	if err != nil {
 		return nil, err
 	}
 
+	p.depth -= 1
+
 	err = p.parseToken(RightBrace)
 	if err != nil {
 		return nil, err
 	}
 
+	el.Position = position
+
 	return el, nil
 
 }
@@ -146,7 +191,10 @@ func (p *Parser) parseToken(tt TokenType) error {
 		p.next()
 		return nil
 	} else {
-		return fmt.Errorf("%w: expected %s, got %s", ErrUnexpectedToken, tt.String(), p.peek().Type.String())
+		return &Error{
+			Position: p.currentToken().Position,
+			Err:      fmt.Errorf("%w: expected %s, got %s", ErrUnexpectedToken, tt.String(), p.peek().Type.String()),
+		}
 	}
 }
 
@@ -0,0 +1,22 @@
+package parser
+
+import "fmt"
+
+// Position of a token or a node in the source. Lines and columns start at 1,
+// and the zero value denotes an unknown position.
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
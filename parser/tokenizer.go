@@ -2,6 +2,7 @@ package parser
 
 import (
 	"bufio"
+	"context"
 	"io"
 	"slices"
 	"unicode"
@@ -18,86 +19,131 @@ const (
 )
 
 type Token struct {
-	Type    TokenType
-	Content string
+	Type     TokenType
+	Content  string
+	Position Position
 }
 
 type Tokenizer struct {
-	input *bufio.Reader
+	input    *bufio.Reader
+	position Position
+	previous Position
+	limits   Limits
 }
 
 func NewTokenizer(input *bufio.Reader) Tokenizer {
 	return Tokenizer{
-		input: input,
+		input:    input,
+		position: Position{Line: 1, Column: 1},
 	}
 }
 
+// Tokenize reads the whole input. It panics if the input can't be read.
 func (tokenizer *Tokenizer) Tokenize() []Token {
+	tokens, err := tokenizer.TokenizeContext(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	return tokens
+}
+
+// TokenizeContext reads the whole input. It stops when the context is
+// canceled, or when the number of tokens exceeds the limit.
+func (tokenizer *Tokenizer) TokenizeContext(ctx context.Context) ([]Token, error) {
 	tokens := []Token{}
 	var newTokens []Token
+	untilCheck := 0
 
 	for {
-		r, _, err := tokenizer.input.ReadRune()
+		if untilCheck == 0 {
+			err := ctx.Err()
+			if err != nil {
+				return nil, err
+			}
+
+			untilCheck = checkInterval
+		}
+		untilCheck--
+
+		start := tokenizer.position
+		r, err := tokenizer.readRune()
 		if err != nil {
 			if err == io.EOF {
-				tokens = append(tokens, Token{Type: EOF})
-				return tokens
+				tokens = append(tokens, Token{Type: EOF, Position: start})
+				return tokens, nil
 			}
 
-			panic(err)
+			return nil, err
 		}
 
 		switch r {
 		case '{':
-			newTokens = []Token{{Type: LeftBrace, Content: "{"}}
+			newTokens = []Token{{Type: LeftBrace, Content: "{", Position: start}}
 		case '}':
-			newTokens = []Token{{Type: RightBrace, Content: "}"}}
+			newTokens = []Token{{Type: RightBrace, Content: "}", Position: start}}
 		case '@':
-			newTokens = tokenizer.tokenizeIdentifier("")
+			newTokens, err = tokenizer.tokenizeIdentifier("", start)
 		default:
-			tokenizer.input.UnreadRune()
-			newTokens = tokenizer.tokenizeText("")
+			tokenizer.unreadRune()
+			newTokens, err = tokenizer.tokenizeText("", start)
+		}
+
+		if err != nil {
+			return nil, err
 		}
 
 		tokens = append(tokens, newTokens...)
+
+		if tokenizer.limits.MaxTokens > 0 && len(tokens) > tokenizer.limits.MaxTokens {
+			return nil, &Error{Position: start, Err: &LimitError{Limit: "token count", Max: tokenizer.limits.MaxTokens}}
+		}
 	}
+}
 
+func (tokenizer *Tokenizer) SetLimits(limits Limits) {
+	tokenizer.limits = limits
 }
 
-func (tokenizer *Tokenizer) tokenizeText(start string) []Token {
+func (tokenizer *Tokenizer) tokenizeText(start string, position Position) ([]Token, error) {
 	text := start
 
 	for {
-		r, _, err := tokenizer.input.ReadRune()
-
+		r, err := tokenizer.readRune()
 		if err != nil {
 			if err == io.EOF {
 				break
-			} else {
-				panic(err)
 			}
+
+			return nil, err
 		}
 
 		if slices.Contains([]rune("{}"), r) {
-			tokenizer.input.UnreadRune()
+			tokenizer.unreadRune()
 			break
 		} else if r == '@' {
+			identifierPosition := tokenizer.previous
 
-			nextRune, _, err := tokenizer.input.ReadRune()
+			nextRune, err := tokenizer.readRune()
 			if err != nil {
 				if err == io.EOF {
 					break
-				} else {
-					panic(err)
 				}
+
+				return nil, err
 			}
 
-			if slices.Contains([]rune("{}@"), nextRune) {
+			// like at the start of an identifier, braces and @ are escaped,
+			// and whitespace drops the @
+			if slices.Contains([]rune("{}@"), nextRune) || unicode.IsSpace(nextRune) {
 				r = nextRune
 			} else {
-				identifier := tokenizer.tokenizeIdentifier(string(nextRune))
+				identifier, err := tokenizer.tokenizeIdentifier(string(nextRune), identifierPosition)
+				if err != nil {
+					return nil, err
+				}
 
-				return append([]Token{{Type: Text, Content: text}}, identifier...)
+				return append([]Token{{Type: Text, Content: text, Position: position}}, identifier...), nil
 			}
 		}
 
@@ -105,34 +151,35 @@ func (tokenizer *Tokenizer) tokenizeText(start string) []Token {
 	}
 
 	return []Token{
-		{Type: Text, Content: text},
-	}
+		{Type: Text, Content: text, Position: position},
+	}, nil
 }
 
-// Tokenize identifier or a escaped sequence: `@@`, `@{`, `@}`
-func (tokenizer *Tokenizer) tokenizeIdentifier(start string) []Token {
+// Tokenize identifier or a escaped sequence: `@@`, `@{`, `@}`. Identifiers end
+// at braces, `@` and any whitespace, and an `@` followed by whitespace is
+// dropped.
+func (tokenizer *Tokenizer) tokenizeIdentifier(start string, position Position) ([]Token, error) {
 	identifier := start
-	firstPass := true
+	// an identifier started in text already has its first rune, so the next
+	// brace or whitespace ends it instead of escaping it
+	firstPass := start == ""
 
 	for {
-		r, _, err := tokenizer.input.ReadRune()
+		r, err := tokenizer.readRune()
 		if err != nil {
 			if err == io.EOF {
 				break
-			} else {
-				panic(err)
 			}
+
+			return nil, err
 		}
 
-		if slices.Contains([]rune("{} @"), r) {
+		if slices.Contains([]rune("{}@"), r) || unicode.IsSpace(r) {
 			if firstPass {
-				return tokenizer.tokenizeText(string(r))
+				return tokenizer.tokenizeText(string(r), position)
 			}
 
-			err := tokenizer.input.UnreadRune()
-			if err != nil {
-				panic(err)
-			}
+			tokenizer.unreadRune()
 
 			break
 		}
@@ -142,10 +189,37 @@ func (tokenizer *Tokenizer) tokenizeIdentifier(start string) []Token {
 	}
 
 	return []Token{
-		Token{Type: Identifier, Content: identifier},
+		{Type: Identifier, Content: identifier, Position: position},
+	}, nil
+}
+
+func (tokenizer *Tokenizer) readRune() (rune, error) {
+	r, _, err := tokenizer.input.ReadRune()
+	if err != nil {
+		return r, err
+	}
+
+	tokenizer.previous = tokenizer.position
+	if r == '\n' {
+		tokenizer.position.Line += 1
+		tokenizer.position.Column = 1
+	} else {
+		tokenizer.position.Column += 1
+	}
+
+	return r, nil
+}
+
+func (tokenizer *Tokenizer) unreadRune() {
+	err := tokenizer.input.UnreadRune()
+	if err != nil {
+		panic(err)
 	}
+
+	tokenizer.position = tokenizer.previous
 }
 
+// EqualStreams compares token types and contents, ignoring positions
 func EqualStreams(a, b []Token) bool {
 	if a == nil {
 		return b == nil
@@ -160,7 +234,7 @@ func EqualStreams(a, b []Token) bool {
 	}
 
 	for i := range a {
-		if a[i] != b[i] {
+		if a[i].Type != b[i].Type || a[i].Content != b[i].Content {
 			return false
 		}
 	}
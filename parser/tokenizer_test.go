@@ -88,6 +88,36 @@ func TestTokenizer(t *testing.T) {
 				{Type: parser.EOF, Content: ""},
 			},
 		},
+		{
+			input: "@p\n@a",
+			expectedResult: []parser.Token{
+				{Type: parser.Identifier, Content: "p"},
+				{Type: parser.Text, Content: "\n"},
+				{Type: parser.Identifier, Content: "a"},
+				{Type: parser.EOF, Content: ""},
+			},
+		},
+		{
+			input: "a @b{c}",
+			expectedResult: []parser.Token{
+				{Type: parser.Text, Content: "a "},
+				{Type: parser.Identifier, Content: "b"},
+				{Type: parser.LeftBrace, Content: "{"},
+				{Type: parser.Text, Content: "c"},
+				{Type: parser.RightBrace, Content: "}"},
+				{Type: parser.EOF, Content: ""},
+			},
+		},
+		{
+			input: "a @b @c",
+			expectedResult: []parser.Token{
+				{Type: parser.Text, Content: "a "},
+				{Type: parser.Identifier, Content: "b"},
+				{Type: parser.Text, Content: " "},
+				{Type: parser.Identifier, Content: "c"},
+				{Type: parser.EOF, Content: ""},
+			},
+		},
 		{
 			input: "@{@@@}",
 			expectedResult: []parser.Token{
@@ -114,6 +144,79 @@ func TestTokenizer(t *testing.T) {
 
 }
 
+func TestTokenizerWhitespaceEndsIdentifier(t *testing.T) {
+	testCases := []struct {
+		input          string
+		expectedResult []parser.Token
+	}{
+		{
+			input: "@br\tx",
+			expectedResult: []parser.Token{
+				{Type: parser.Identifier, Content: "br"},
+				{Type: parser.Text, Content: "\tx"},
+				{Type: parser.EOF, Content: ""},
+			},
+		},
+		{
+			input: "@br\nx",
+			expectedResult: []parser.Token{
+				{Type: parser.Identifier, Content: "br"},
+				{Type: parser.Text, Content: "\nx"},
+				{Type: parser.EOF, Content: ""},
+			},
+		},
+		{
+			input: "@br\r\nx",
+			expectedResult: []parser.Token{
+				{Type: parser.Identifier, Content: "br"},
+				{Type: parser.Text, Content: "\r\nx"},
+				{Type: parser.EOF, Content: ""},
+			},
+		},
+		{
+			input: "@br\u00a0x",
+			expectedResult: []parser.Token{
+				{Type: parser.Identifier, Content: "br"},
+				{Type: parser.Text, Content: "\u00a0x"},
+				{Type: parser.EOF, Content: ""},
+			},
+		},
+		{
+			input: "@ x",
+			expectedResult: []parser.Token{
+				{Type: parser.Text, Content: " x"},
+				{Type: parser.EOF, Content: ""},
+			},
+		},
+		{
+			input: "a @ b",
+			expectedResult: []parser.Token{
+				{Type: parser.Text, Content: "a  b"},
+				{Type: parser.EOF, Content: ""},
+			},
+		},
+		{
+			input: "a @\nb",
+			expectedResult: []parser.Token{
+				{Type: parser.Text, Content: "a \nb"},
+				{Type: parser.EOF, Content: ""},
+			},
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(fmt.Sprintf("TestTokenizerWhitespaceEndsIdentifier%d", i), func(t *testing.T) {
+			reader := bufio.NewReader(strings.NewReader(testCase.input))
+			tokenizer := parser.NewTokenizer(reader)
+
+			result := tokenizer.Tokenize()
+			if !parser.EqualStreams(result, testCase.expectedResult) {
+				t.Errorf("Streams are not equal. Expected %v got %v", testCase.expectedResult, result)
+			}
+		})
+	}
+}
+
 func Test_EqualStreams(t *testing.T) {
 	if !parser.EqualStreams(nil, nil) {
 		t.Error("Streams should be equal")
@@ -139,3 +242,31 @@ func Test_EqualStreams(t *testing.T) {
 		t.Error("Streams should be equal")
 	}
 }
+
+func TestTokenizerPositions(t *testing.T) {
+	input := "@p{a\nb @b{c}}"
+	expectedPositions := []parser.Position{
+		{Line: 1, Column: 1},
+		{Line: 1, Column: 3},
+		{Line: 1, Column: 4},
+		{Line: 2, Column: 3},
+		{Line: 2, Column: 5},
+		{Line: 2, Column: 6},
+		{Line: 2, Column: 7},
+		{Line: 2, Column: 8},
+		{Line: 2, Column: 9},
+	}
+
+	tokenizer := parser.NewTokenizer(bufio.NewReader(strings.NewReader(input)))
+	result := tokenizer.Tokenize()
+
+	if len(result) != len(expectedPositions) {
+		t.Fatalf("Expected %d tokens, got %v", len(expectedPositions), result)
+	}
+
+	for i, token := range result {
+		if token.Position != expectedPositions[i] {
+			t.Errorf("Token %v: expected position %v, got %v", token, expectedPositions[i], token.Position)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+// Package query implements a small selector language over parser trees.
+//
+// A selector is a list of steps separated by combinators:
+//
+//	section p > link
+//	link:2 > b
+//	* > todo
+//
+// A step matches a command by name, or any command with `*`. The optional
+// `:n` suffix restricts the next step to the n-th argument of the command
+// (arguments are counted from 1). On the last step, it only requires the
+// command to have at least n arguments. Steps separated by whitespace are in a
+// descendant relation, while `>` requires a direct child.
+//
+// Atex has no command IDs or parameters yet, so selectors like `section#api`
+// and `link[href]` are rejected with ErrUnsupportedSelector. They will be
+// supported once the parser reads IDs and parameters.
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ubavic/mint/parser"
+)
+
+var ErrInvalidSelector = errors.New("invalid selector")
+var ErrUnsupportedSelector = errors.New("unsupported selector")
+
+type combinator int
+
+const (
+	descendant combinator = iota
+	child
+)
+
+type step struct {
+	name     string
+	argument int
+	// relation to the previous step
+	combinator combinator
+}
+
+type Selector struct {
+	source string
+	steps  []step
+}
+
+// Match is a command matched by a selector, together with its ancestors
+// (from the root to the direct parent).
+type Match struct {
+	Command   *parser.Command
+	Ancestors []*parser.Command
+}
+
+type ancestor struct {
+	command  *parser.Command
+	argument int
+}
+
+func Compile(selector string) (*Selector, error) {
+	s := &Selector{source: selector}
+
+	fields := strings.Fields(strings.ReplaceAll(selector, ">", " > "))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: empty selector", ErrInvalidSelector)
+	}
+
+	nextCombinator := descendant
+	expectStep := true
+
+	for _, field := range fields {
+		if field == ">" {
+			if expectStep {
+				return nil, fmt.Errorf("%w: unexpected '>' in \"%s\"", ErrInvalidSelector, selector)
+			}
+			nextCombinator = child
+			expectStep = true
+			continue
+		}
+
+		st, err := parseStep(field)
+		if err != nil {
+			return nil, err
+		}
+
+		st.combinator = nextCombinator
+		s.steps = append(s.steps, st)
+		nextCombinator = descendant
+		expectStep = false
+	}
+
+	if expectStep {
+		return nil, fmt.Errorf("%w: selector \"%s\" ends with '>'", ErrInvalidSelector, selector)
+	}
+
+	return s, nil
+}
+
+func MustCompile(selector string) *Selector {
+	s, err := Compile(selector)
+	if err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+func parseStep(field string) (step, error) {
+	if strings.ContainsAny(field, "#") {
+		return step{}, fmt.Errorf("%w: command IDs are not supported by the parser (\"%s\")", ErrUnsupportedSelector, field)
+	}
+
+	if strings.ContainsAny(field, "[]") {
+		return step{}, fmt.Errorf("%w: command parameters are not supported by the parser (\"%s\")", ErrUnsupportedSelector, field)
+	}
+
+	name, index, found := strings.Cut(strings.TrimPrefix(field, "@"), ":")
+	if name == "" {
+		return step{}, fmt.Errorf("%w: missing command name in \"%s\"", ErrInvalidSelector, field)
+	}
+
+	st := step{name: name}
+
+	if found {
+		argument, err := strconv.Atoi(index)
+		if err != nil || argument < 1 {
+			return step{}, fmt.Errorf("%w: invalid argument index in \"%s\"", ErrInvalidSelector, field)
+		}
+		st.argument = argument
+	}
+
+	return st, nil
+}
+
+func (s *Selector) String() string {
+	return s.source
+}
+
+// Find returns all commands in the tree that match the selector, in document
+// order.
+func (s *Selector) Find(root parser.Element) []Match {
+	matches := []Match{}
+	s.walk(root, []ancestor{}, &matches)
+	return matches
+}
+
+func (s *Selector) walk(element parser.Element, ancestors []ancestor, matches *[]Match) {
+	switch v := element.(type) {
+	case *parser.Block:
+		for _, node := range v.Nodes {
+			s.walk(node, ancestors, matches)
+		}
+	case *parser.Command:
+		if s.matches(v, ancestors) {
+			match := Match{Command: v, Ancestors: make([]*parser.Command, len(ancestors))}
+			for i, a := range ancestors {
+				match.Ancestors[i] = a.command
+			}
+			*matches = append(*matches, match)
+		}
+
+		for i, arg := range v.Arguments {
+			s.walk(arg, append(ancestors[:len(ancestors):len(ancestors)], ancestor{command: v, argument: i + 1}), matches)
+		}
+	}
+}
+
+func (s *Selector) matches(command *parser.Command, ancestors []ancestor) bool {
+	last := len(s.steps) - 1
+	if !s.steps[last].matchesName(command.Name) || s.steps[last].argument > len(command.Arguments) {
+		return false
+	}
+
+	return s.matchAncestors(last, ancestors)
+}
+
+// matchAncestors checks steps before i against the ancestors of the node
+// matched by step i
+func (s *Selector) matchAncestors(i int, ancestors []ancestor) bool {
+	if i == 0 {
+		return true
+	}
+
+	previous := s.steps[i-1]
+
+	switch s.steps[i].combinator {
+	case child:
+		if len(ancestors) == 0 {
+			return false
+		}
+
+		parent := ancestors[len(ancestors)-1]
+		return previous.matches(parent) && s.matchAncestors(i-1, ancestors[:len(ancestors)-1])
+	default:
+		for j := len(ancestors) - 1; j >= 0; j-- {
+			if previous.matches(ancestors[j]) && s.matchAncestors(i-1, ancestors[:j]) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+func (st step) matchesName(name string) bool {
+	return st.name == "*" || st.name == name
+}
+
+func (st step) matches(a ancestor) bool {
+	if !st.matchesName(a.command.Name) {
+		return false
+	}
+
+	return st.argument == 0 || st.argument == a.argument
+}
@@ -0,0 +1,87 @@
+package query_test
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ubavic/mint/parser"
+	"github.com/ubavic/mint/query"
+)
+
+const document = `@section{Intro}{
+  @p{See @link{docs}{https://example.com} and @b{@link{bold}{x}}.}
+  @link{top}{y}
+}
+@p{@link{other}{@b{z}}}`
+
+func TestQuery(t *testing.T) {
+	testCases := []struct {
+		selector          string
+		expectedPositions []string
+	}{
+		{selector: "link", expectedPositions: []string{"2:10", "2:50", "3:3", "5:4"}},
+		{selector: "p > link", expectedPositions: []string{"2:10", "5:4"}},
+		{selector: "p link", expectedPositions: []string{"2:10", "2:50", "5:4"}},
+		{selector: "section p > link", expectedPositions: []string{"2:10"}},
+		{selector: "section > link", expectedPositions: []string{"3:3"}},
+		{selector: "section:1 link", expectedPositions: []string{}},
+		{selector: "section:2 > *", expectedPositions: []string{"2:3", "3:3"}},
+		{selector: "link:2 > b", expectedPositions: []string{"5:17"}},
+		{selector: "link:1 b", expectedPositions: []string{}},
+		{selector: "@b > link", expectedPositions: []string{"2:50"}},
+	}
+
+	tokenizer := parser.NewTokenizer(bufio.NewReader(strings.NewReader(document)))
+	p := parser.NewParser(tokenizer.Tokenize(), &parser.OptimisticValidator{})
+	root, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%s\"", err)
+	}
+
+	for i, testCase := range testCases {
+		t.Run(
+			fmt.Sprintf("TestQuery%d", i),
+			func(t *testing.T) {
+				selector, err := query.Compile(testCase.selector)
+				if err != nil {
+					t.Fatalf("Expected no error, got \"%s\"", err)
+				}
+
+				positions := []string{}
+				for _, match := range selector.Find(root) {
+					positions = append(positions, match.Command.Position.String())
+				}
+
+				if strings.Join(positions, " ") != strings.Join(testCase.expectedPositions, " ") {
+					t.Errorf("Selector \"%s\": expected matches at %v, got %v", testCase.selector, testCase.expectedPositions, positions)
+				}
+			},
+		)
+	}
+}
+
+func TestQueryInvalidSelectors(t *testing.T) {
+	testCases := []struct {
+		selector      string
+		expectedError error
+	}{
+		{selector: "", expectedError: query.ErrInvalidSelector},
+		{selector: "> p", expectedError: query.ErrInvalidSelector},
+		{selector: "p >", expectedError: query.ErrInvalidSelector},
+		{selector: "p > > b", expectedError: query.ErrInvalidSelector},
+		{selector: "p:x", expectedError: query.ErrInvalidSelector},
+		{selector: "p:0", expectedError: query.ErrInvalidSelector},
+		{selector: "section#api p", expectedError: query.ErrUnsupportedSelector},
+		{selector: "link[href]", expectedError: query.ErrUnsupportedSelector},
+	}
+
+	for _, testCase := range testCases {
+		_, err := query.Compile(testCase.selector)
+		if !errors.Is(err, testCase.expectedError) {
+			t.Errorf("Selector \"%s\": expected error \"%v\", got \"%v\"", testCase.selector, testCase.expectedError, err)
+		}
+	}
+}
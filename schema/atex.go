@@ -0,0 +1,418 @@
+package schema
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/ubavic/mint/parser"
+)
+
+var ErrInvalidAtexSchema = errors.New("invalid atex schema")
+
+// noText disallows text at the root of atex schemas
+var noText = false
+
+// metaSchema describes schemas written in atex. Commands of the source and
+// targets are written as:
+//
+//	@command{p}{1}{
+//	  @description{Paragraph}
+//	  @allowChildren{inline}
+//	}
+//	@group{inline}{b, link}
+//	@target{HTML}{
+//	  @extension{html}
+//	  @expression{p}{<p>$1</p>}
+//	}
+//
+// Arguments holding names, numbers and lists are trimmed, while expressions,
+// templates and escape maps are taken as they are written.
+var metaSchema = Schema{
+	Source: Source{
+		AllowedRootCommands: "schema",
+		RootText:            &noText,
+		Commands: []Command{
+			{Command: "mint", Arguments: 1, Description: "Version of the schema format"},
+			{Command: "name", Arguments: 1, Description: "Name of the schema"},
+			{Command: "author", Arguments: 1, Description: "Author of the schema"},
+			{Command: "version", Arguments: 1, Description: "Version of the schema"},
+			{Command: "import", Arguments: 1, Variadic: true, Description: "Path of an imported schema, and an optional namespace"},
+			{Command: "rootChildren", Arguments: 1, Description: "Group of commands allowed at the root"},
+			{Command: "rootText", Arguments: 1, Description: "Whether text is allowed at the root: true or false"},
+			{Command: "tocCommand", Arguments: 1, Description: "Command replaced with the table of contents"},
+			{Command: "bibliographyCommand", Arguments: 1, Description: "Command replaced with the list of cited references"},
+			{Command: "command", Arguments: 2, Variadic: true, AllowChildren: "command", Description: "Name, number of arguments, and an optional body of a command"},
+			{Command: "group", Arguments: 2, Variadic: true, AllowChildren: "group", Description: "Name, a list of commands, and an optional body of a group"},
+			{Command: "counter", Arguments: 1, Variadic: true, Description: "Name of a counter, and an optional counter it is numbered within"},
+			{Command: "target", Arguments: 2, AllowChildren: "target", Description: "Name and a body of a target"},
+
+			{Command: "description", Arguments: 1, Description: "Description of a command"},
+			{Command: "allowChildren", Arguments: 1, Description: "Group of commands allowed in arguments"},
+			{Command: "text", Arguments: 1, Description: "Whether text is allowed in arguments or in the group: true or false"},
+			{Command: "variadic", Arguments: 0, Description: "Marks a command accepting more arguments"},
+			{Command: "label", Arguments: 1, Description: "Index of the argument labeling the command, which requires a counter"},
+			{Command: "cite", Arguments: 1, Description: "Index of the argument holding citation keys"},
+			{Command: "heading", Arguments: 2, Description: "Level and index of the title argument of a heading"},
+			{Command: "include", Arguments: 0, Description: "Marks a command replaced with the named file"},
+
+			{Command: "groups", Arguments: 1, Description: "List of included groups"},
+			{Command: "exclude", Arguments: 1, Description: "List of excluded commands"},
+			{Command: "excludeGroups", Arguments: 1, Description: "List of groups whose commands are excluded"},
+			{Command: "ordered", Arguments: 0, Description: "Marks a group whose commands appear in order"},
+			{Command: "occurrence", Arguments: 3, Description: "Command, and the least and the greatest number of its occurrences"},
+
+			{Command: "extends", Arguments: 1, Description: "Target whose definitions are inherited"},
+			{Command: "extension", Arguments: 1, Description: "Extension of output files"},
+			{Command: "escape", Arguments: 1, Description: "Built-in escaper of text"},
+			{Command: "escapeMap", Arguments: 2, Description: "Text and its replacement"},
+			{Command: "typography", Arguments: 1, Description: "List of typographic transforms"},
+			{Command: "document", Arguments: 1, Description: "Template of the document"},
+			{Command: "toc", Arguments: 1, Description: "Template of the table of contents"},
+			{Command: "bibliography", Arguments: 1, Description: "Template of the list of cited references"},
+			{Command: "expression", Arguments: 2, Description: "Command and its expression"},
+			{Command: "template", Arguments: 2, Description: "Command and its template"},
+			{Command: "packages", Arguments: 2, Description: "Command and a list of packages"},
+			{Command: "collect", Arguments: 3, Description: "Command, bucket and a template of collected items"},
+			{Command: "flush", Arguments: 2, Description: "Command and a bucket written in its place"},
+		},
+		Groups: []Group{
+			{Name: "schema", Commands: []string{"mint", "name", "author", "version", "import", "rootChildren", "rootText", "tocCommand", "bibliographyCommand", "command", "group", "counter", "target"}},
+			{Name: "command", Commands: []string{"description", "allowChildren", "text", "variadic", "counter", "label", "cite", "heading", "include"}},
+			{Name: "group", Commands: []string{"groups", "exclude", "excludeGroups", "ordered", "occurrence", "text"}},
+			{Name: "target", Commands: []string{"extends", "extension", "escape", "escapeMap", "typography", "document", "toc", "bibliography", "expression", "template", "packages", "collect", "flush"}},
+		},
+	},
+}
+
+// compiledMetaSchema returns metaSchema indexed, so schemas in atex are not
+// validated with linear searches
+var compiledMetaSchema = sync.OnceValue(func() *Schema {
+	s := metaSchema
+
+	err := s.Compile()
+	if err != nil {
+		panic(err)
+	}
+
+	return &s
+})
+
+// decodeAtex reads a schema written in atex. Problems are appended to issues
+// when given, and returned as positioned errors otherwise.
+func decodeAtex(file string, data []byte, issues *[]Issue) (*Schema, error) {
+	tokenizer := parser.NewTokenizer(bufio.NewReader(bytes.NewReader(data)))
+	tokens := tokenizer.Tokenize()
+
+	p := parser.NewParser(tokens, compiledMetaSchema())
+	document, err := p.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	r := atexReader{file: file, schema: &Schema{}, errs: []*parser.Error{}}
+	r.readSchema(document)
+
+	if issues != nil {
+		for _, err := range r.errs {
+			*issues = append(*issues, Issue{File: file, Line: err.Position.Line, Message: err.Err.Error()})
+		}
+
+		return r.schema, nil
+	}
+
+	errs := []error{}
+	for _, err := range r.errs {
+		errs = append(errs, err)
+	}
+
+	return r.schema, errors.Join(errs...)
+}
+
+type atexReader struct {
+	file   string
+	schema *Schema
+	errs   []*parser.Error
+}
+
+func (r *atexReader) fail(position parser.Position, format string, args ...any) {
+	r.errs = append(r.errs, &parser.Error{
+		Position: position,
+		Err:      fmt.Errorf("%w: %s", ErrInvalidAtexSchema, fmt.Sprintf(format, args...)),
+	})
+}
+
+func (r *atexReader) location(command *parser.Command) location {
+	return location{file: r.file, line: command.Position.Line}
+}
+
+// commands returns commands of the element, and reports non-whitespace text.
+// Commands are already checked against the group by the parser, and text at
+// the root is not allowed by the meta-schema.
+func (r *atexReader) commands(element parser.Element, group string) []*parser.Command {
+	commands := []*parser.Command{}
+
+	for _, node := range element.Content() {
+		switch node := node.(type) {
+		case *parser.Command:
+			commands = append(commands, node)
+		case *parser.TextContent:
+			text := strings.TrimSpace(node.TextContent)
+			if text != "" {
+				r.fail(textPosition(node), "unexpected text \"%s\" in %s", excerpt(text), group)
+			}
+		}
+	}
+
+	return commands
+}
+
+// maxArguments reports variadic commands of the meta-schema given too many
+// arguments
+func (r *atexReader) maxArguments(command *parser.Command, max int) bool {
+	if len(command.Arguments) > max {
+		r.fail(command.Position, "command %s takes at most %d arguments, but %d is given", command.Name, max, len(command.Arguments))
+		return false
+	}
+
+	return true
+}
+
+// text returns the argument as it is written. Commands in it are reported.
+func (r *atexReader) text(command *parser.Command, i int) string {
+	argument := command.Arguments[i]
+
+	for _, node := range argument.Content() {
+		if nested, ok := node.(*parser.Command); ok {
+			r.fail(nested.Position, "unexpected command %s in argument %d of %s", nested.Name, i+1, command.Name)
+		}
+	}
+
+	return parser.PlainText(argument)
+}
+
+func (r *atexReader) name(command *parser.Command, i int) string {
+	return strings.TrimSpace(r.text(command, i))
+}
+
+func (r *atexReader) number(command *parser.Command, i int) int {
+	text := r.name(command, i)
+
+	n, err := strconv.Atoi(text)
+	if err != nil {
+		r.fail(command.Position, "argument %d of %s is not a number: \"%s\"", i+1, command.Name, excerpt(text))
+	}
+
+	return n
+}
+
+func (r *atexReader) boolean(command *parser.Command, i int) *bool {
+	text := r.name(command, i)
+
+	value, err := strconv.ParseBool(text)
+	if err != nil {
+		r.fail(command.Position, "argument %d of %s is not true or false: \"%s\"", i+1, command.Name, excerpt(text))
+	}
+
+	return &value
+}
+
+// list splits the argument at commas and whitespace
+func (r *atexReader) list(command *parser.Command, i int) []string {
+	return strings.FieldsFunc(r.text(command, i), func(c rune) bool {
+		return c == ',' || unicode.IsSpace(c)
+	})
+}
+
+func (r *atexReader) readSchema(document parser.Element) {
+	s := r.schema
+
+	for _, command := range r.commands(document, "schema") {
+		switch command.Name {
+		case "mint":
+			s.Mint = r.name(command, 0)
+			if _, err := checkVersion(s.Mint); err != nil {
+				r.errs = append(r.errs, &parser.Error{Position: command.Position, Err: err})
+			}
+		case "name":
+			s.Name = r.name(command, 0)
+		case "author":
+			s.Author = r.name(command, 0)
+		case "version":
+			s.Version = r.name(command, 0)
+		case "import":
+			if r.maxArguments(command, 2) {
+				i := Import{Path: r.name(command, 0)}
+				if len(command.Arguments) == 2 {
+					i.Namespace = r.name(command, 1)
+				}
+				s.Imports = append(s.Imports, i)
+			}
+		case "rootChildren":
+			s.Source.AllowedRootCommands = r.name(command, 0)
+		case "rootText":
+			s.Source.RootText = r.boolean(command, 0)
+		case "tocCommand":
+			s.Source.TocCommand = r.name(command, 0)
+		case "bibliographyCommand":
+			s.Source.BibliographyCommand = r.name(command, 0)
+		case "command":
+			if r.maxArguments(command, 3) {
+				s.Source.Commands = append(s.Source.Commands, r.readCommand(command))
+			}
+		case "group":
+			if r.maxArguments(command, 3) {
+				s.Source.Groups = append(s.Source.Groups, r.readGroup(command))
+			}
+		case "counter":
+			if r.maxArguments(command, 2) {
+				counter := Counter{Name: r.name(command, 0), location: r.location(command)}
+				if len(command.Arguments) == 2 {
+					counter.Within = r.name(command, 1)
+				}
+				s.Source.Counters = append(s.Source.Counters, counter)
+			}
+		case "target":
+			s.Targets = append(s.Targets, r.readTarget(command))
+		}
+	}
+}
+
+func (r *atexReader) readCommand(definition *parser.Command) Command {
+	c := Command{
+		Command:   r.name(definition, 0),
+		Arguments: r.number(definition, 1),
+		location:  r.location(definition),
+	}
+
+	if len(definition.Arguments) < 3 {
+		return c
+	}
+
+	for _, command := range r.commands(definition.Arguments[2], "command") {
+		switch command.Name {
+		case "description":
+			c.Description = r.name(command, 0)
+		case "allowChildren":
+			c.AllowChildren = r.name(command, 0)
+		case "text":
+			c.Text = r.boolean(command, 0)
+		case "variadic":
+			c.Variadic = true
+		case "counter":
+			if r.maxArguments(command, 1) {
+				c.Counter = r.name(command, 0)
+			}
+		case "label":
+			c.Label = r.number(command, 0)
+		case "cite":
+			c.Cite = r.number(command, 0)
+		case "heading":
+			c.Heading = &Heading{Level: r.number(command, 0), Title: r.number(command, 1)}
+		case "include":
+			c.Include = true
+		}
+	}
+
+	return c
+}
+
+func (r *atexReader) readGroup(definition *parser.Command) Group {
+	g := Group{
+		Name:     r.name(definition, 0),
+		Commands: r.list(definition, 1),
+		location: r.location(definition),
+	}
+
+	if len(definition.Arguments) < 3 {
+		return g
+	}
+
+	for _, command := range r.commands(definition.Arguments[2], "group") {
+		switch command.Name {
+		case "groups":
+			g.Groups = append(g.Groups, r.list(command, 0)...)
+		case "exclude":
+			g.Exclude = append(g.Exclude, r.list(command, 0)...)
+		case "excludeGroups":
+			g.ExcludeGroups = append(g.ExcludeGroups, r.list(command, 0)...)
+		case "ordered":
+			g.Ordered = true
+		case "text":
+			g.Text = r.boolean(command, 0)
+		case "occurrence":
+			g.Occurrences = append(g.Occurrences, Occurrence{
+				Command: r.name(command, 0),
+				Min:     r.number(command, 1),
+				Max:     r.number(command, 2),
+			})
+		}
+	}
+
+	return g
+}
+
+func (r *atexReader) readTarget(definition *parser.Command) Target {
+	t := Target{
+		Name:     r.name(definition, 0),
+		location: r.location(definition),
+	}
+
+	// targetCommand returns the target command, defining it on first use
+	targetCommand := func(command *parser.Command) *TargetCommand {
+		name := r.name(command, 0)
+
+		i := slices.IndexFunc(t.Commands, func(c TargetCommand) bool {
+			return c.Command == name
+		})
+		if i < 0 {
+			t.Commands = append(t.Commands, TargetCommand{Command: name, location: r.location(command)})
+			i = len(t.Commands) - 1
+		}
+
+		return &t.Commands[i]
+	}
+
+	for _, command := range r.commands(definition.Arguments[1], "target") {
+		switch command.Name {
+		case "extends":
+			t.Extends = r.name(command, 0)
+		case "extension":
+			t.Extension = r.name(command, 0)
+		case "escape":
+			t.Escape = r.name(command, 0)
+		case "escapeMap":
+			if t.EscapeMap == nil {
+				t.EscapeMap = map[string]string{}
+			}
+			t.EscapeMap[r.text(command, 0)] = r.text(command, 1)
+		case "typography":
+			t.Typography = r.list(command, 0)
+		case "document":
+			t.Document = r.text(command, 0)
+		case "toc":
+			t.Toc = r.text(command, 0)
+		case "bibliography":
+			t.Bibliography = r.text(command, 0)
+		case "expression":
+			targetCommand(command).Expression = r.text(command, 1)
+		case "template":
+			targetCommand(command).Template = r.text(command, 1)
+		case "packages":
+			c := targetCommand(command)
+			c.Packages = append(c.Packages, r.list(command, 1)...)
+		case "collect":
+			targetCommand(command).Collect = &Collect{Bucket: r.name(command, 1), Template: r.text(command, 2)}
+		case "flush":
+			targetCommand(command).Flush = r.name(command, 1)
+		}
+	}
+
+	return t
+}
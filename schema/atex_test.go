@@ -0,0 +1,150 @@
+package schema_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ubavic/mint/schema"
+	"gopkg.in/yaml.v3"
+)
+
+func TestLoadAtexExample(t *testing.T) {
+	examples := os.DirFS("../example")
+
+	fromYAML, err := schema.LoadFS(examples, "schema.yaml")
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	fromAtex, err := schema.LoadFS(examples, "schema.atex")
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	expected, _ := yaml.Marshal(fromYAML)
+	got, _ := yaml.Marshal(fromAtex)
+
+	if string(got) != string(expected) {
+		t.Errorf("Expected atex schema equal to YAML schema:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestLoadAtex(t *testing.T) {
+	files := fstest.MapFS{
+		"schema.atex": {Data: []byte(`
+@import{math.yaml}{math}
+@tocCommand{toc}
+@counter{section}
+@counter{figure}{section}
+@command{section}{1}{
+  @counter{section}
+  @heading{1}{1}
+  @label{1}
+}
+@command{list}{0}{@variadic}
+@command{toc}{0}
+@target{HTML}{
+  @escapeMap{~}{&nbsp;}
+  @toc{<nav>@{@{range .Entries@}@}@{@{.Text@}@}@{@{end@}@}</nav>}
+  @expression{section}{<h2>$1</h2>}
+  @expression{list}{<ul>$1</ul>}
+}
+`)},
+		"math.yaml": {Data: []byte("source:\n  commands:\n    - command: frac\n      arguments: 2\ntargets:\n  - name: HTML\n    commands:\n      - command: frac\n        expression: $1/$2\n")},
+	}
+
+	s, err := schema.LoadFS(files, "schema.atex")
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	section, err := s.GetCommand("section")
+	if err != nil || section.Counter != "section" || section.Heading.Level != 1 || section.Label != 1 {
+		t.Errorf("Expected section with counter, heading and label, got %v", section)
+	}
+
+	list, err := s.GetCommand("list")
+	if err != nil || !list.Variadic {
+		t.Errorf("Expected variadic list, got %v", list)
+	}
+
+	figure, err := s.GetCounter("figure")
+	if err != nil || figure.Within != "section" {
+		t.Errorf("Expected figure counter within section, got %v", figure)
+	}
+
+	html, err := s.GetTarget("HTML")
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	if html.EscapeMap["~"] != "&nbsp;" || html.Toc != "<nav>{{range .Entries}}{{.Text}}{{end}}</nav>" {
+		t.Errorf("Expected escape map and toc template, got %v and %s", html.EscapeMap, html.Toc)
+	}
+
+	if len(html.Commands) != 3 || html.Commands[2].Command != "math:frac" {
+		t.Errorf("Expected imported command math:frac, got %v", html.Commands)
+	}
+}
+
+func TestLoadAtexErrors(t *testing.T) {
+	testCases := []struct {
+		schema        string
+		expectedError error
+		expectedText  string
+	}{
+		{
+			schema:        "@command{p}{one}",
+			expectedError: schema.ErrInvalidAtexSchema,
+			expectedText:  "schema.atex: 1:1: invalid atex schema: argument 2 of command is not a number: \"one\"",
+		},
+		{
+			schema:        "@command{p}{1}\n\n@target{HTML}{\n  @description{Text}\n}",
+			expectedError: schema.ErrCommandNotAllowed,
+			expectedText:  "schema.atex: parsing error: 4:3: command not allowed: command description in command target",
+		},
+		{
+			schema:        "@command{p}{1}\nstray text in the schema root",
+			expectedError: schema.ErrTextNotAllowed,
+			expectedText:  "schema.atex: parsing error: 2:1: text not allowed: \"stray text in the sc…\" in the root",
+		},
+		{
+			schema:        "@target{HTML}{\n  stray\n}",
+			expectedError: schema.ErrInvalidAtexSchema,
+			expectedText:  "schema.atex: 2:3: invalid atex schema: unexpected text \"stray\" in target",
+		},
+		{
+			schema:        "@counter{a}{b}{c}",
+			expectedError: schema.ErrInvalidAtexSchema,
+			expectedText:  "schema.atex: 1:1: invalid atex schema: command counter takes at most 2 arguments, but 3 is given",
+		},
+		{
+			schema:        "@name{Test}\n@command{p}",
+			expectedError: schema.ErrCommandInvalidArguments,
+			expectedText:  "schema.atex: 2:1: command has invalid arguments: command command requires at least 2 arguments, but 1 is given",
+		},
+		{
+			schema:        "@mint{v9.0}",
+			expectedError: schema.ErrUnsupportedVersion,
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(fmt.Sprintf("TestLoadAtexErrors%d", i), func(t *testing.T) {
+			files := fstest.MapFS{"schema.atex": {Data: []byte(testCase.schema)}}
+
+			_, err := schema.LoadFS(files, "schema.atex")
+			if !errors.Is(err, testCase.expectedError) {
+				t.Fatalf("Expected error \"%v\", got \"%v\"", testCase.expectedError, err)
+			}
+
+			if testCase.expectedText != "" && !strings.Contains(err.Error(), testCase.expectedText) {
+				t.Errorf("Expected error \"%s\", got \"%v\"", testCase.expectedText, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,386 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// location of a definition in a schema file. File is empty for schemas
+// decoded from bytes.
+type location struct {
+	file string
+	line int
+}
+
+// Issue is a problem found in a schema
+type Issue struct {
+	File string
+	// Line is zero for issues that don't belong to a single definition
+	Line    int
+	Message string
+}
+
+func (i Issue) String() string {
+	location := i.File
+	if i.Line > 0 && location != "" {
+		location += ":" + strconv.Itoa(i.Line)
+	} else if i.Line > 0 {
+		location = strconv.Itoa(i.Line)
+	}
+
+	if location == "" {
+		return i.Message
+	}
+
+	return location + ": " + i.Message
+}
+
+func (l location) issue(format string, args ...any) Issue {
+	return Issue{File: l.file, Line: l.line, Message: fmt.Sprintf(format, args...)}
+}
+
+// locate records lines of commands, groups, counters, targets and target
+// commands
+func (s *Schema) locate(file string, root *yaml.Node) {
+	if len(root.Content) == 0 {
+		return
+	}
+
+	document := root.Content[0]
+	source := mappingValue(document, "source")
+
+	for i, node := range sequence(mappingValue(source, "commands"), len(s.Source.Commands)) {
+		s.Source.Commands[i].location = location{file: file, line: node.Line}
+	}
+
+	for i, node := range sequence(mappingValue(source, "groups"), len(s.Source.Groups)) {
+		s.Source.Groups[i].location = location{file: file, line: node.Line}
+	}
+
+	for i, node := range sequence(mappingValue(source, "counters"), len(s.Source.Counters)) {
+		s.Source.Counters[i].location = location{file: file, line: node.Line}
+	}
+
+	for i, node := range sequence(mappingValue(document, "targets"), len(s.Targets)) {
+		target := &s.Targets[i]
+		target.location = location{file: file, line: node.Line}
+
+		for j, command := range sequence(mappingValue(node, "commands"), len(target.Commands)) {
+			target.Commands[j].location = location{file: file, line: command.Line}
+		}
+	}
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// sequence returns at most n items of the sequence node, or all items when n
+// is negative
+func sequence(node *yaml.Node, n int) []*yaml.Node {
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return nil
+	}
+
+	if n < 0 {
+		return node.Content
+	}
+
+	return node.Content[:min(n, len(node.Content))]
+}
+
+// unknownKeyIssue converts a message of yaml.TypeError, like "line 3: field
+// author not found in type schema.Schema"
+func unknownKeyIssue(file, message string) Issue {
+	issue := Issue{File: file, Message: message}
+
+	lineText, rest, ok := strings.Cut(strings.TrimPrefix(message, "line "), ": ")
+	if line, err := strconv.Atoi(lineText); ok && err == nil {
+		issue.Line = line
+		issue.Message = rest
+	}
+
+	if field, ok := strings.CutPrefix(issue.Message, "field "); ok {
+		name, _, _ := strings.Cut(field, " ")
+		issue.Message = "unknown key " + name
+	}
+
+	return issue
+}
+
+// LintFS decodes the named schema and its imports, and returns unknown keys
+// together with issues found by Check. An error is returned only when the
+// schema can't be decoded.
+func LintFS(fsys fs.FS, name string) ([]Issue, error) {
+	issues := []Issue{}
+
+	s, err := decodeFile(fsys, name, []string{}, &issues)
+	if err != nil && s == nil {
+		return nil, err
+	}
+
+	if err != nil {
+		issues = append(issues, Issue{File: name, Message: err.Error()})
+	}
+
+	issues = append(issues, s.Check()...)
+	sortIssues(issues)
+
+	return issues, nil
+}
+
+// Check returns issues of the schema: duplicate definitions, groups with
+// undefined commands, unused groups, commands that are not allowed anywhere,
+// targets lacking commands, invalid expressions and templates, and errors
+// reported by Load. Issues are ordered by file and line.
+func (s *Schema) Check() []Issue {
+	// targets are resolved on a copy, so the schema is not changed
+	c := *s
+	c.Targets = slices.Clone(s.Targets)
+
+	issues := []Issue{}
+	issues = append(issues, errorIssues(c.resolveTargets())...)
+	issues = append(issues, c.checkDuplicates()...)
+	issues = append(issues, c.checkGroups()...)
+	issues = append(issues, c.checkTargetCoverage()...)
+	issues = append(issues, errorIssues(errors.Join(c.checkCounters(), c.checkHeadings(), c.checkCitations(), c.checkOccurrences()))...)
+
+	for _, target := range c.Targets {
+		err := c.checkTarget(target)
+		if err != nil {
+			issues = append(issues, target.location.issue("%v", err))
+		}
+
+		for i, err := range c.checkTargetCommands(target) {
+			if err != nil {
+				issues = append(issues, target.Commands[i].location.issue("%v", err))
+			}
+		}
+	}
+
+	sortIssues(issues)
+
+	return issues
+}
+
+func sortIssues(issues []Issue) {
+	slices.SortStableFunc(issues, func(a, b Issue) int {
+		if a.File != b.File {
+			return strings.Compare(a.File, b.File)
+		}
+
+		return a.Line - b.Line
+	})
+}
+
+// errorIssues converts joined errors to issues without location
+func errorIssues(err error) []Issue {
+	if err == nil {
+		return []Issue{}
+	}
+
+	issues := []Issue{}
+
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			issues = append(issues, errorIssues(err)...)
+		}
+		return issues
+	}
+
+	return append(issues, Issue{Message: err.Error()})
+}
+
+func (s *Schema) checkDuplicates() []Issue {
+	issues := []Issue{}
+
+	issues = append(issues, duplicates(s.Source.Commands, "command", "", func(c Command) (string, location) { return c.Command, c.location })...)
+	issues = append(issues, duplicates(s.Source.Groups, "group", "", func(g Group) (string, location) { return g.Name, g.location })...)
+	issues = append(issues, duplicates(s.Source.Counters, "counter", "", func(c Counter) (string, location) { return c.Name, c.location })...)
+	issues = append(issues, duplicates(s.Targets, "target", "", func(t Target) (string, location) { return t.Name, t.location })...)
+
+	for _, target := range s.Targets {
+		issues = append(issues, duplicates(target.Commands, "command", " in target "+target.Name, func(c TargetCommand) (string, location) { return c.Command, c.location })...)
+	}
+
+	return issues
+}
+
+func duplicates[T any](items []T, kind, where string, name func(T) (string, location)) []Issue {
+	issues := []Issue{}
+	first := map[string]location{}
+
+	for _, item := range items {
+		itemName, itemLocation := name(item)
+
+		if previous, ok := first[itemName]; ok {
+			issues = append(issues, itemLocation.issue("duplicate %s %s%s, first defined at line %d", kind, itemName, where, previous.line))
+			continue
+		}
+
+		first[itemName] = itemLocation
+	}
+
+	return issues
+}
+
+// checkGroups reports cycles of included groups, groups with undefined
+// commands and groups, unused groups, and commands that can't appear in a
+// valid document. Without a root group, every command is allowed at the root.
+func (s *Schema) checkGroups() []Issue {
+	issues := []Issue{}
+
+	r := groupResolver{schema: s, expanded: map[string][]string{}}
+	for _, group := range s.Source.Groups {
+		if _, err := r.expand(group.Name, []string{}); err != nil && !errors.Is(err, ErrGroupNotFound) {
+			issues = append(issues, group.location.issue("%v", err))
+		}
+	}
+
+	direct := []string{}
+
+	if s.Source.AllowedRootCommands != "" {
+		direct = append(direct, s.Source.AllowedRootCommands)
+		if _, err := s.GetGroup(s.Source.AllowedRootCommands); err != nil {
+			issues = append(issues, Issue{Message: fmt.Sprintf("root group %s is not defined", s.Source.AllowedRootCommands)})
+		}
+	}
+
+	for _, command := range s.Source.Commands {
+		if command.AllowChildren == "" {
+			continue
+		}
+
+		direct = append(direct, command.AllowChildren)
+		if _, err := s.GetGroup(command.AllowChildren); err != nil {
+			issues = append(issues, command.location.issue("group %s allowed in command %s is not defined", command.AllowChildren, command.Command))
+		}
+	}
+
+	used := map[string]bool{}
+	var use func(name string)
+	use = func(name string) {
+		group, err := s.GetGroup(name)
+		if used[name] || err != nil {
+			return
+		}
+
+		used[name] = true
+		for _, included := range slices.Concat(group.Groups, group.ExcludeGroups) {
+			use(included)
+		}
+	}
+
+	for _, name := range direct {
+		use(name)
+	}
+
+	for _, group := range s.Source.Groups {
+		if !used[group.Name] {
+			issues = append(issues, group.location.issue("group %s is never used", group.Name))
+		}
+
+		for _, name := range group.Commands {
+			if _, err := s.GetCommand(name); err != nil {
+				issues = append(issues, group.location.issue("group %s refers to undefined command %s", group.Name, name))
+			}
+		}
+
+		for _, name := range group.Exclude {
+			if _, err := s.GetCommand(name); err != nil {
+				issues = append(issues, group.location.issue("group %s excludes undefined command %s", group.Name, name))
+			}
+		}
+
+		for _, name := range slices.Concat(group.Groups, group.ExcludeGroups) {
+			if _, err := s.GetGroup(name); err != nil {
+				issues = append(issues, group.location.issue("group %s refers to undefined group %s", group.Name, name))
+			}
+		}
+	}
+
+	if s.Source.AllowedRootCommands == "" {
+		return issues
+	}
+
+	// commands are allowed as the validator accepts them: in the root group,
+	// and in arguments of allowed commands, where any command is accepted
+	// without AllowChildren
+	allowed := map[string]bool{}
+	pending := slices.Clone(r.expanded[s.Source.AllowedRootCommands])
+
+	for len(pending) > 0 {
+		name := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		command, err := s.GetCommand(name)
+		if allowed[name] || err != nil {
+			continue
+		}
+
+		allowed[name] = true
+		if command.AllowChildren == "" {
+			for _, c := range s.Source.Commands {
+				pending = append(pending, c.Command)
+			}
+		} else {
+			pending = append(pending, r.expanded[command.AllowChildren]...)
+		}
+	}
+
+	for _, command := range s.Source.Commands {
+		if !allowed[command.Command] && !command.Include {
+			issues = append(issues, command.location.issue("command %s is not allowed anywhere", command.Command))
+		}
+	}
+
+	return issues
+}
+
+// checkTargetCoverage reports commands missing in targets, and target
+// commands that are not defined in the source. Include commands, and table of
+// contents and bibliography commands of targets with such templates, need no
+// target command.
+func (s *Schema) checkTargetCoverage() []Issue {
+	issues := []Issue{}
+
+	for _, target := range s.Targets {
+		defined := map[string]bool{}
+
+		for _, targetCommand := range target.Commands {
+			defined[targetCommand.Command] = true
+
+			if _, err := s.GetCommand(targetCommand.Command); err != nil {
+				issues = append(issues, targetCommand.location.issue("target %s defines undefined command %s", target.Name, targetCommand.Command))
+			}
+		}
+
+		for _, command := range s.Source.Commands {
+			switch {
+			case defined[command.Command], command.Include:
+			case target.Toc != "" && command.Command == s.Source.TocCommand:
+			case target.Bibliography != "" && command.Command == s.Source.BibliographyCommand:
+			default:
+				issues = append(issues, target.location.issue("target %s lacks command %s", target.Name, command.Command))
+			}
+		}
+	}
+
+	return issues
+}
@@ -0,0 +1,200 @@
+package schema_test
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ubavic/mint/parser"
+	"github.com/ubavic/mint/schema"
+)
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	_, err := schema.Load([]byte(`
+source:
+  commands:
+    - command: p
+      arguments: 1
+      colour: red
+`))
+	if err == nil {
+		t.Fatalf("Expected error for unknown key, got nil")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	s, err := schema.Decode([]byte(`
+source:
+  allowedRootChildren: block
+  commands:
+    - command: p
+      arguments: 1
+      allowChildren: inline
+    - command: b
+      arguments: 1
+    - command: p
+      arguments: 1
+  groups:
+    - name: block
+      commands: [p, missing]
+    - name: unused
+      commands: [b]
+targets:
+  - name: HTML
+    commands:
+      - command: p
+        expression: "<p>$2</p>"
+      - command: ghost
+        expression: ""
+`))
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	expected := []string{
+		"5: group inline allowed in command p is not defined",
+		"8: command b is not allowed anywhere",
+		"10: duplicate command p, first defined at line 5",
+		"13: group block refers to undefined command missing",
+		"15: group unused is never used",
+		"18: target HTML lacks command b",
+		"20: target HTML, command p: invalid expression: placeholder $2 exceeds 1 arguments",
+		"22: target HTML defines undefined command ghost",
+	}
+
+	issues := s.Check()
+	if len(issues) != len(expected) {
+		t.Fatalf("Expected %d issues, got %v", len(expected), issues)
+	}
+
+	for i, issue := range issues {
+		if fmt.Sprintf("%d: %s", issue.Line, issue.Message) != expected[i] {
+			t.Errorf("Expected issue \"%s\", got \"%d: %s\"", expected[i], issue.Line, issue.Message)
+		}
+	}
+}
+
+func TestCheckAllowedCommands(t *testing.T) {
+	testCases := []struct {
+		schema   string
+		input    string
+		expected []string
+	}{
+		{
+			// arguments of p have no group, so b is allowed there
+			schema: `
+source:
+  allowedRootChildren: blocks
+  commands:
+    - command: p
+      arguments: 1
+    - command: b
+      arguments: 1
+  groups:
+    - name: blocks
+      commands: [p]
+`,
+			input:    "@p{@b{x}}",
+			expected: []string{},
+		},
+		{
+			// inner is allowed only in hidden, which is not allowed anywhere
+			schema: `
+source:
+  allowedRootChildren: blocks
+  commands:
+    - command: p
+      arguments: 1
+      allowChildren: inline
+    - command: b
+      arguments: 1
+      allowChildren: none
+    - command: hidden
+      arguments: 1
+      allowChildren: inner
+    - command: inner
+      arguments: 1
+      allowChildren: none
+  groups:
+    - name: blocks
+      commands: [p]
+    - name: inline
+      commands: [b]
+    - name: inner
+      commands: [inner]
+    - name: none
+      commands: []
+`,
+			input: "@p{@b{x}}",
+			expected: []string{
+				"command hidden is not allowed anywhere",
+				"command inner is not allowed anywhere",
+			},
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(fmt.Sprintf("TestCheckAllowedCommands%d", i), func(t *testing.T) {
+			s, err := schema.Load([]byte(testCase.schema))
+			if err != nil {
+				t.Fatalf("Expected no error, got \"%v\"", err)
+			}
+
+			issues := s.Check()
+			if len(issues) != len(testCase.expected) {
+				t.Fatalf("Expected %d issues, got %v", len(testCase.expected), issues)
+			}
+
+			for i, issue := range issues {
+				if issue.Message != testCase.expected[i] {
+					t.Errorf("Expected issue \"%s\", got \"%s\"", testCase.expected[i], issue.Message)
+				}
+			}
+
+			tokenizer := parser.NewTokenizer(bufio.NewReader(strings.NewReader(testCase.input)))
+			p := parser.NewParser(tokenizer.Tokenize(), s)
+
+			_, err = p.Parse()
+			if err != nil {
+				t.Errorf("Expected no error, got \"%v\"", err)
+			}
+		})
+	}
+}
+
+func TestCheckExample(t *testing.T) {
+	data, err := os.ReadFile("../example/schema.yaml")
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	s, err := schema.Load(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	issues := s.Check()
+	if len(issues) != 0 {
+		t.Errorf("Expected no issues, got %v", issues)
+	}
+}
+
+func TestLintFS(t *testing.T) {
+	files := fstest.MapFS{
+		"main.yaml":  {Data: []byte("imports:\n  - path: other.yaml\nsource:\n  commands:\n    - command: p\n      arguments: 1\n")},
+		"other.yaml": {Data: []byte("source:\n  commands:\n    - command: b\n      argumnts: 1\n")},
+	}
+
+	issues, err := schema.LintFS(files, "main.yaml")
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	expected := "[other.yaml:4: unknown key argumnts]"
+	if fmt.Sprint(issues) != expected {
+		t.Errorf("Expected issues %s, got %v", expected, issues)
+	}
+}
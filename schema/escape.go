@@ -0,0 +1,167 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var ErrUnknownEscaper = errors.New("unknown escaper")
+var ErrUnknownTransform = errors.New("unknown text transform")
+
+var escapers = map[string][]string{
+	"none": {},
+	"html": {"&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&quot;", "'", "&#39;"},
+	"xml":  {"&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&quot;", "'", "&apos;"},
+	"latex": {
+		"\\", "\\textbackslash{}",
+		"{", "\\{",
+		"}", "\\}",
+		"$", "\\$",
+		"&", "\\&",
+		"#", "\\#",
+		"%", "\\%",
+		"_", "\\_",
+		"^", "\\textasciicircum{}",
+		"~", "\\textasciitilde{}",
+	},
+}
+
+// argumentFilters can be applied to plain text of arguments in expressions,
+// like `${2|url}`
+var argumentFilters = []string{"raw", "url", "html", "xml", "latex"}
+
+// typographic transforms receive the character preceding the text
+var typographicTransforms = map[string]func(text string, previous rune) string{
+	"quotes":   smartQuotes,
+	"dashes":   withoutPrevious(strings.NewReplacer("---", "—", "--", "–").Replace),
+	"ellipsis": withoutPrevious(strings.NewReplacer("...", "…").Replace),
+}
+
+func withoutPrevious(transform func(string) string) func(string, rune) string {
+	return func(text string, _ rune) string {
+		return transform(text)
+	}
+}
+
+// TextTransform returns a function applied to text content of documents. It
+// applies typographic transforms, and then escapes characters with the
+// built-in escaper and the custom escape map. Text content is split by
+// commands, so the function receives the last character of the preceding
+// text, or zero at the start of the document.
+func (t *Target) TextTransform() (func(text string, previous rune) string, error) {
+	transforms := []func(string, rune) string{}
+
+	for _, name := range t.Typography {
+		transform, ok := typographicTransforms[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s in target %s", ErrUnknownTransform, name, t.Name)
+		}
+		transforms = append(transforms, transform)
+	}
+
+	escapeName := t.Escape
+	if escapeName == "" {
+		escapeName = "none"
+	}
+
+	builtIn, ok := escapers[escapeName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s in target %s", ErrUnknownEscaper, t.Escape, t.Name)
+	}
+
+	pairs := []string{}
+	custom := make([]string, 0, len(t.EscapeMap))
+	for from := range t.EscapeMap {
+		custom = append(custom, from)
+	}
+	slices.Sort(custom)
+
+	for _, from := range custom {
+		pairs = append(pairs, from, t.EscapeMap[from])
+	}
+
+	for i := 0; i < len(builtIn); i += 2 {
+		if _, ok := t.EscapeMap[builtIn[i]]; !ok {
+			pairs = append(pairs, builtIn[i], builtIn[i+1])
+		}
+	}
+
+	if len(pairs) > 0 {
+		transforms = append(transforms, withoutPrevious(strings.NewReplacer(pairs...).Replace))
+	}
+
+	return func(text string, previous rune) string {
+		for _, transform := range transforms {
+			text = transform(text, previous)
+		}
+		return text
+	}, nil
+}
+
+// EscapeArgument applies an argument filter to plain text
+func EscapeArgument(filter, text string) (string, error) {
+	switch filter {
+	case "raw":
+		return text, nil
+	case "url":
+		return EscapeURL(text), nil
+	}
+
+	pairs, ok := escapers[filter]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownEscaper, filter)
+	}
+
+	return strings.NewReplacer(pairs...).Replace(text), nil
+}
+
+// EscapeURL trims the text and percent-encodes characters that are not
+// allowed in URLs. Reserved characters, like `/`, `?` and `&`, are kept.
+func EscapeURL(text string) string {
+	const allowed = "-._~:/?#[]@!$&'()*+,;=%"
+
+	var builder strings.Builder
+	for _, b := range []byte(strings.TrimSpace(text)) {
+		if b < 0x80 && (isAlphanumeric(b) || strings.IndexByte(allowed, b) >= 0) {
+			builder.WriteByte(b)
+		} else {
+			fmt.Fprintf(&builder, "%%%02X", b)
+		}
+	}
+
+	return builder.String()
+}
+
+func isAlphanumeric(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
+
+// smartQuotes replaces straight quotes with typographic ones. A quote is
+// opening at the start of the document, or after whitespace or an opening
+// bracket. previous precedes the text, and is zero at the start.
+func smartQuotes(text string, previous rune) string {
+	var builder strings.Builder
+
+	for _, r := range text {
+		opening := previous == 0 || strings.ContainsRune(" \t\n\r([{“‘", previous)
+
+		switch {
+		case r == '"' && opening:
+			builder.WriteRune('“')
+		case r == '"':
+			builder.WriteRune('”')
+		case r == '\'' && opening:
+			builder.WriteRune('‘')
+		case r == '\'':
+			builder.WriteRune('’')
+		default:
+			builder.WriteRune(r)
+		}
+
+		previous = r
+	}
+
+	return builder.String()
+}
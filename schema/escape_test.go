@@ -0,0 +1,119 @@
+package schema_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ubavic/mint/schema"
+)
+
+func TestTextTransform(t *testing.T) {
+	testCases := []struct {
+		target         schema.Target
+		input          string
+		previous       rune
+		expectedResult string
+		expectedError  error
+	}{
+		{
+			target:         schema.Target{},
+			input:          "<b> & 100% \"x\"",
+			expectedResult: "<b> & 100% \"x\"",
+		},
+		{
+			target:         schema.Target{Escape: "html"},
+			input:          "<b> & \"x\"",
+			expectedResult: "&lt;b&gt; &amp; &quot;x&quot;",
+		},
+		{
+			target:         schema.Target{Escape: "xml"},
+			input:          "it's",
+			expectedResult: "it&apos;s",
+		},
+		{
+			target:         schema.Target{Escape: "latex"},
+			input:          "100% of $5 & a_b #1 {x} \\ ~^",
+			expectedResult: "100\\% of \\$5 \\& a\\_b \\#1 \\{x\\} \\textbackslash{} \\textasciitilde{}\\textasciicircum{}",
+		},
+		{
+			target:         schema.Target{Escape: "html", EscapeMap: map[string]string{"<": "‹", "€": "&euro;"}},
+			input:          "<5€>",
+			expectedResult: "‹5&euro;&gt;",
+		},
+		{
+			target:         schema.Target{Typography: []string{"quotes", "dashes", "ellipsis"}},
+			input:          "\"Wait...\" -- she said --- 'it's (\"fine\")'",
+			expectedResult: "“Wait…” – she said — ‘it’s (“fine”)’",
+		},
+		{
+			target:         schema.Target{Escape: "html", Typography: []string{"quotes"}},
+			input:          "\"<\"",
+			expectedResult: "“&lt;”",
+		},
+		{
+			target:         schema.Target{Typography: []string{"quotes"}},
+			input:          "'s\"",
+			previous:       'x',
+			expectedResult: "’s”",
+		},
+		{
+			target:         schema.Target{Typography: []string{"quotes"}},
+			input:          "\"x\"",
+			previous:       '\n',
+			expectedResult: "“x”",
+		},
+		{
+			target:        schema.Target{Escape: "rtf"},
+			expectedError: schema.ErrUnknownEscaper,
+		},
+		{
+			target:        schema.Target{Typography: []string{"ligatures"}},
+			expectedError: schema.ErrUnknownTransform,
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(
+			fmt.Sprintf("TestTextTransform%d", i),
+			func(t *testing.T) {
+				transform, err := testCase.target.TextTransform()
+				if !errors.Is(err, testCase.expectedError) {
+					t.Fatalf("Expected error \"%v\", got \"%v\"", testCase.expectedError, err)
+				}
+
+				if err != nil {
+					return
+				}
+
+				if result := transform(testCase.input, testCase.previous); result != testCase.expectedResult {
+					t.Errorf("Expected \"%s\", got \"%s\"", testCase.expectedResult, result)
+				}
+			},
+		)
+	}
+}
+
+func TestEscapeArgument(t *testing.T) {
+	testCases := []struct {
+		filter         string
+		input          string
+		expectedResult string
+	}{
+		{filter: "raw", input: "<a & b>", expectedResult: "<a & b>"},
+		{filter: "html", input: "<a & b>", expectedResult: "&lt;a &amp; b&gt;"},
+		{filter: "url", input: " https://example.com/a b?q=č&x=\"1\" ", expectedResult: "https://example.com/a%20b?q=%C4%8D&x=%221%22"},
+		{filter: "url", input: "https://example.com/a%20b", expectedResult: "https://example.com/a%20b"},
+	}
+
+	for _, testCase := range testCases {
+		result, err := schema.EscapeArgument(testCase.filter, testCase.input)
+		if err != nil {
+			t.Fatalf("Expected no error, got \"%v\"", err)
+		}
+
+		if result != testCase.expectedResult {
+			t.Errorf("Filter %s: expected \"%s\", got \"%s\"", testCase.filter, testCase.expectedResult, result)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidExpression = errors.New("invalid expression")
+
+// Expression is a parsed target expression. Placeholders `$1` to `$9` and
+// `${n}` are replaced with rendered arguments, and `$$` produces a literal `$`.
+// A placeholder with a filter, like `${2|url}`, is replaced with the plain
+// text of the argument escaped by the filter. `${#}` is replaced with the
+// number of the command given by its counter.
+type Expression struct {
+	segments []segment
+}
+
+// segment is either a literal text, a placeholder of an argument, or the
+// number placeholder
+type segment struct {
+	text     string
+	argument int
+	filter   string
+	number   bool
+}
+
+func ParseExpression(expression string) (*Expression, error) {
+	e := &Expression{}
+	var text strings.Builder
+
+	for i := 0; i < len(expression); i++ {
+		if expression[i] != '$' {
+			text.WriteByte(expression[i])
+			continue
+		}
+
+		if i+1 >= len(expression) {
+			return nil, fmt.Errorf("%w: \"%s\" ends with $, use $$ for a literal $", ErrInvalidExpression, expression)
+		}
+
+		next := expression[i+1]
+		argument := 0
+		filter := ""
+		number := false
+
+		switch {
+		case next == '$':
+			text.WriteByte('$')
+			i++
+			continue
+		case next >= '1' && next <= '9':
+			argument = int(next - '0')
+			i++
+		case next == '{':
+			end := strings.IndexByte(expression[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unclosed placeholder in \"%s\"", ErrInvalidExpression, expression)
+			}
+
+			index, filterName, hasFilter := strings.Cut(expression[i+2:i+end], "|")
+			if strings.TrimSpace(index) == "#" && !hasFilter {
+				number = true
+				i += end
+				break
+			}
+
+			if hasFilter {
+				filter = strings.TrimSpace(filterName)
+				if !slices.Contains(argumentFilters, filter) {
+					return nil, fmt.Errorf("%w: unknown filter \"%s\" in \"%s\"", ErrInvalidExpression, filter, expression)
+				}
+			}
+
+			n, err := strconv.Atoi(strings.TrimSpace(index))
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: invalid placeholder %s in \"%s\"", ErrInvalidExpression, expression[i:i+end+1], expression)
+			}
+
+			argument = n
+			i += end
+		default:
+			return nil, fmt.Errorf("%w: invalid placeholder $%c in \"%s\", use $$ for a literal $", ErrInvalidExpression, next, expression)
+		}
+
+		if text.Len() > 0 {
+			e.segments = append(e.segments, segment{text: text.String()})
+			text.Reset()
+		}
+
+		e.segments = append(e.segments, segment{argument: argument, filter: filter, number: number})
+	}
+
+	if text.Len() > 0 {
+		e.segments = append(e.segments, segment{text: text.String()})
+	}
+
+	return e, nil
+}
+
+// MaxArgument returns the highest argument referenced by a placeholder, or
+// zero if there are no placeholders
+func (e *Expression) MaxArgument() int {
+	result := 0
+
+	for _, s := range e.segments {
+		result = max(result, s.argument)
+	}
+
+	return result
+}
+
+// Numbered reports whether the expression has the number placeholder
+func (e *Expression) Numbered() bool {
+	return slices.ContainsFunc(e.segments, func(s segment) bool {
+		return s.number
+	})
+}
+
+// Execute writes literal segments and the number to w, and calls argument
+// for each argument placeholder with its filter. Arguments are counted from 1.
+func (e *Expression) Execute(w io.Writer, number string, argument func(i int, filter string) error) error {
+	for _, s := range e.segments {
+		if s.number {
+			_, err := io.WriteString(w, number)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if s.argument == 0 {
+			_, err := io.WriteString(w, s.text)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		err := argument(s.argument, s.filter)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
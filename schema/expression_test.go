@@ -0,0 +1,156 @@
+package schema_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ubavic/mint/schema"
+)
+
+func TestExpression(t *testing.T) {
+	testCases := []struct {
+		expression          string
+		expectedResult      string
+		expectedMaxArgument int
+		expectedError       error
+	}{
+		{expression: "", expectedResult: ""},
+		{expression: "<p>$1</p>", expectedResult: "<p>[1]</p>", expectedMaxArgument: 1},
+		{expression: "$2$1", expectedResult: "[2][1]", expectedMaxArgument: 2},
+		{expression: "$10", expectedResult: "[1]0", expectedMaxArgument: 1},
+		{expression: "${10}", expectedResult: "[10]", expectedMaxArgument: 10},
+		{expression: "$$1 costs $$$1", expectedResult: "$1 costs $[1]", expectedMaxArgument: 1},
+		{expression: "ä$1ö", expectedResult: "ä[1]ö", expectedMaxArgument: 1},
+		{expression: "<a href=\"${2|url}\">$1</a>", expectedResult: "<a href=\"[2url]\">[1]</a>", expectedMaxArgument: 2},
+		{expression: "${ 1 | raw }", expectedResult: "[1raw]", expectedMaxArgument: 1},
+		{expression: "<h2>${#}. $1</h2>", expectedResult: "<h2>3.2. [1]</h2>", expectedMaxArgument: 1},
+		{expression: "${ # }$$#", expectedResult: "3.2$#"},
+		{expression: "${#|url}", expectedError: schema.ErrInvalidExpression},
+		{expression: "${1|bold}", expectedError: schema.ErrInvalidExpression},
+		{expression: "$x$", expectedError: schema.ErrInvalidExpression},
+		{expression: "cost: $", expectedError: schema.ErrInvalidExpression},
+		{expression: "$0", expectedError: schema.ErrInvalidExpression},
+		{expression: "${1", expectedError: schema.ErrInvalidExpression},
+		{expression: "${a}", expectedError: schema.ErrInvalidExpression},
+		{expression: "${0}", expectedError: schema.ErrInvalidExpression},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(
+			fmt.Sprintf("TestExpression%d", i),
+			func(t *testing.T) {
+				expression, err := schema.ParseExpression(testCase.expression)
+				if !errors.Is(err, testCase.expectedError) {
+					t.Fatalf("Expected error \"%v\", got \"%v\"", testCase.expectedError, err)
+				}
+
+				if err != nil {
+					return
+				}
+
+				if expression.MaxArgument() != testCase.expectedMaxArgument {
+					t.Errorf("Expected max argument %d, got %d", testCase.expectedMaxArgument, expression.MaxArgument())
+				}
+
+				var result strings.Builder
+				expression.Execute(&result, "3.2", func(i int, filter string) error {
+					fmt.Fprintf(&result, "[%d%s]", i, filter)
+					return nil
+				})
+
+				if result.String() != testCase.expectedResult {
+					t.Errorf("Expected \"%s\", got \"%s\"", testCase.expectedResult, result.String())
+				}
+			},
+		)
+	}
+}
+
+func TestLoadChecksExpressions(t *testing.T) {
+	testCases := []struct {
+		schema        string
+		expectedError error
+	}{
+		{
+			schema: `
+source:
+  commands:
+    - command: link
+      arguments: 2
+targets:
+  - name: HTML
+    commands:
+      - command: link
+        expression: "<a href=\"$2\">$1</a>"
+`,
+		},
+		{
+			schema: `
+source:
+  commands:
+    - command: link
+      arguments: 2
+targets:
+  - name: HTML
+    commands:
+      - command: link
+        expression: "<a href=\"$3\">$1</a>"
+`,
+			expectedError: schema.ErrInvalidExpression,
+		},
+		{
+			schema: `
+targets:
+  - name: Latex
+    commands:
+      - command: math
+        expression: "$x$"
+`,
+			expectedError: schema.ErrInvalidExpression,
+		},
+		{
+			schema: `
+source:
+  counters:
+    - name: figure
+  commands:
+    - command: figure
+      arguments: 1
+      counter: figure
+targets:
+  - name: HTML
+    commands:
+      - command: figure
+        expression: "<figure>$1<figcaption>Figure ${#}</figcaption></figure>"
+`,
+		},
+		{
+			schema: `
+source:
+  commands:
+    - command: figure
+      arguments: 1
+targets:
+  - name: HTML
+    commands:
+      - command: figure
+        expression: "<figure>$1<figcaption>Figure ${#}</figcaption></figure>"
+`,
+			expectedError: schema.ErrInvalidExpression,
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(
+			fmt.Sprintf("TestLoadChecksExpressions%d", i),
+			func(t *testing.T) {
+				_, err := schema.Load([]byte(testCase.schema))
+				if !errors.Is(err, testCase.expectedError) {
+					t.Fatalf("Expected error \"%v\", got \"%v\"", testCase.expectedError, err)
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,122 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+var ErrTargetCycle = errors.New("target inheritance cycle")
+var ErrUnknownOverride = errors.New("override of command not defined in extended target")
+
+// resolveTargets replaces each target that extends another with the result
+// of inheritance
+func (s *Schema) resolveTargets() error {
+	r := targetResolver{schema: s, visited: map[int]bool{}}
+	errs := []error{}
+
+	for i := range s.Targets {
+		errs = append(errs, r.resolve(i, []string{}))
+	}
+
+	return errors.Join(errs...)
+}
+
+type targetResolver struct {
+	schema  *Schema
+	visited map[int]bool
+}
+
+func (r *targetResolver) resolve(i int, chain []string) error {
+	target := &r.schema.Targets[i]
+	if r.visited[i] || target.Extends == "" {
+		return nil
+	}
+
+	chain = append(chain, target.Name)
+	if slices.Contains(chain[:len(chain)-1], target.Name) {
+		return fmt.Errorf("target %s: %w: %s", chain[0], ErrTargetCycle, strings.Join(chain, " -> "))
+	}
+
+	// errors are reported once for each target
+	defer func() { r.visited[i] = true }()
+
+	parent := slices.IndexFunc(r.schema.Targets, func(t Target) bool {
+		return t.Name == target.Extends
+	})
+	if parent < 0 {
+		return fmt.Errorf("target %s extends %s: %w", target.Name, target.Extends, ErrTargetNotFound)
+	}
+
+	err := r.resolve(parent, chain)
+	if err != nil {
+		return err
+	}
+
+	merged, err := inherit(r.schema.Targets[parent], *target)
+	*target = merged
+
+	return err
+}
+
+// inherit returns the child with unset fields taken from the parent
+func inherit(parent, child Target) (Target, error) {
+	fill(&child, parent)
+
+	errs := []error{}
+	commands := slices.Clone(parent.Commands)
+
+	for _, override := range child.Commands {
+		i := slices.IndexFunc(commands, func(c TargetCommand) bool {
+			return c.Command == override.Command
+		})
+
+		if i < 0 {
+			errs = append(errs, fmt.Errorf("target %s: %w %s: %s", child.Name, ErrUnknownOverride, parent.Name, override.Command))
+			continue
+		}
+
+		commands[i] = override
+	}
+
+	child.Commands = commands
+
+	return child, errors.Join(errs...)
+}
+
+// fill sets unset fields of the child, except commands, from the parent.
+// Escape maps are merged.
+func fill(child *Target, parent Target) {
+	if child.Extension == "" {
+		child.Extension = parent.Extension
+	}
+
+	if child.Escape == "" {
+		child.Escape = parent.Escape
+	}
+
+	if child.Typography == nil {
+		child.Typography = parent.Typography
+	}
+
+	if child.Document == "" {
+		child.Document = parent.Document
+	}
+
+	if child.Toc == "" {
+		child.Toc = parent.Toc
+	}
+
+	if child.Bibliography == "" {
+		child.Bibliography = parent.Bibliography
+	}
+
+	escapeMap := maps.Clone(parent.EscapeMap)
+	if escapeMap == nil {
+		escapeMap = map[string]string{}
+	}
+	maps.Copy(escapeMap, child.EscapeMap)
+	child.EscapeMap = escapeMap
+}
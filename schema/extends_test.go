@@ -0,0 +1,133 @@
+package schema_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ubavic/mint/schema"
+)
+
+func TestExtends(t *testing.T) {
+	s, err := schema.Load([]byte(`
+source:
+  commands:
+    - command: p
+      arguments: 1
+    - command: b
+      arguments: 1
+targets:
+  - name: Email
+    extends: HTML
+    escapeMap:
+      "'": "&#39;"
+    commands:
+      - command: b
+        expression: "<strong>$1</strong>"
+  - name: HTML
+    extension: html
+    escape: html
+    escapeMap:
+      "~": "&nbsp;"
+    document: "<body>{{.Body}}</body>"
+    commands:
+      - command: p
+        expression: "<p>$1</p>"
+      - command: b
+        expression: "<b>$1</b>"
+`))
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	email, err := s.GetTarget("Email")
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	if email.Extension != "html" || email.Escape != "html" || email.Document != "<body>{{.Body}}</body>" {
+		t.Errorf("Expected inherited extension, escape and document, got %v", email)
+	}
+
+	if len(email.EscapeMap) != 2 || email.EscapeMap["~"] != "&nbsp;" {
+		t.Errorf("Expected merged escape map, got %v", email.EscapeMap)
+	}
+
+	expected := "p=<p>$1</p> b=<strong>$1</strong>"
+
+	commands := []string{}
+	for _, command := range email.Commands {
+		commands = append(commands, command.Command+"="+command.Expression)
+	}
+
+	if strings.Join(commands, " ") != expected {
+		t.Errorf("Expected commands %s, got %v", expected, commands)
+	}
+
+	html, _ := s.GetTarget("HTML")
+	if html.Commands[1].Expression != "<b>$1</b>" {
+		t.Errorf("Expected extended target to be unchanged, got %v", html.Commands)
+	}
+}
+
+func TestExtendsErrors(t *testing.T) {
+	testCases := []struct {
+		schema        string
+		expectedError error
+	}{
+		{
+			schema: `
+targets:
+  - name: A
+    extends: B
+  - name: B
+    extends: C
+  - name: C
+    extends: A
+`,
+			expectedError: schema.ErrTargetCycle,
+		},
+		{
+			schema: `
+targets:
+  - name: A
+    extends: A
+`,
+			expectedError: schema.ErrTargetCycle,
+		},
+		{
+			schema: `
+targets:
+  - name: A
+    extends: B
+`,
+			expectedError: schema.ErrTargetNotFound,
+		},
+		{
+			schema: `
+targets:
+  - name: A
+    commands:
+      - command: p
+  - name: B
+    extends: A
+    commands:
+      - command: q
+`,
+			expectedError: schema.ErrUnknownOverride,
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(
+			fmt.Sprintf("TestExtendsErrors%d", i),
+			func(t *testing.T) {
+				_, err := schema.Load([]byte(testCase.schema))
+				if !errors.Is(err, testCase.expectedError) {
+					t.Fatalf("Expected error \"%v\", got \"%v\"", testCase.expectedError, err)
+				}
+			},
+		)
+	}
+}
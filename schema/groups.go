@@ -0,0 +1,115 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var ErrGroupCycle = errors.New("group inclusion cycle")
+
+// resolveGroups reports cycles and undefined groups in included groups
+func (s *Schema) resolveGroups() error {
+	r := groupResolver{schema: s, expanded: map[string][]string{}}
+	errs := []error{}
+
+	for _, group := range s.Source.Groups {
+		_, err := r.expand(group.Name, []string{})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// groupCommands returns commands of the group with included groups and
+// exclusions resolved. Groups of loaded schemas are expanded once, and others
+// on each call.
+func (s *Schema) groupCommands(name string) ([]string, error) {
+	if s.index != nil {
+		commands, ok := s.index.expanded[name]
+		if !ok {
+			return nil, ErrGroupNotFound
+		}
+
+		return commands, nil
+	}
+
+	r := groupResolver{schema: s, expanded: map[string][]string{}}
+
+	return r.expand(name, []string{})
+}
+
+type groupResolver struct {
+	schema   *Schema
+	expanded map[string][]string
+}
+
+// expand returns commands of the group. Groups that fail are expanded to no
+// commands afterwards, so each error is reported once.
+func (r *groupResolver) expand(name string, chain []string) ([]string, error) {
+	if commands, ok := r.expanded[name]; ok {
+		return commands, nil
+	}
+
+	commands, err := r.expandGroup(name, chain)
+	if err != nil {
+		r.expanded[name] = []string{}
+		return nil, err
+	}
+
+	r.expanded[name] = commands
+
+	return commands, nil
+}
+
+func (r *groupResolver) expandGroup(name string, chain []string) ([]string, error) {
+	chain = append(chain, name)
+	if slices.Contains(chain[:len(chain)-1], name) {
+		return nil, fmt.Errorf("group %s: %w: %s", chain[0], ErrGroupCycle, strings.Join(chain, " -> "))
+	}
+
+	group, err := r.schema.GetGroup(name)
+	if err != nil {
+		if len(chain) > 1 {
+			return nil, fmt.Errorf("group %s includes %s: %w", chain[len(chain)-2], name, err)
+		}
+		return nil, err
+	}
+
+	commands := []string{}
+	add := func(names []string) {
+		for _, command := range names {
+			if !slices.Contains(commands, command) {
+				commands = append(commands, command)
+			}
+		}
+	}
+
+	add(group.Commands)
+
+	for _, included := range group.Groups {
+		includedCommands, err := r.expand(included, chain)
+		if err != nil {
+			return nil, err
+		}
+
+		add(includedCommands)
+	}
+
+	excluded := slices.Clone(group.Exclude)
+	for _, excludedGroup := range group.ExcludeGroups {
+		excludedCommands, err := r.expand(excludedGroup, chain)
+		if err != nil {
+			return nil, err
+		}
+
+		excluded = append(excluded, excludedCommands...)
+	}
+
+	return slices.DeleteFunc(commands, func(command string) bool {
+		return slices.Contains(excluded, command)
+	}), nil
+}
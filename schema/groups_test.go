@@ -0,0 +1,136 @@
+package schema_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ubavic/mint/schema"
+)
+
+const groupsSchema = `
+source:
+  commands:
+    - command: b
+      arguments: 1
+    - command: i
+      arguments: 1
+    - command: link
+      arguments: 2
+      allowChildren: linkText
+    - command: ref
+      arguments: 1
+    - command: frac
+      arguments: 2
+  groups:
+    - name: inline
+      groups: [formatting, links, math]
+    - name: linkText
+      groups: [inline]
+      excludeGroups: [links]
+      exclude: [frac]
+    - name: formatting
+      commands: [b, i]
+    - name: links
+      commands: [link, ref]
+    - name: math
+      commands: [frac]
+`
+
+func TestGroupComposition(t *testing.T) {
+	loaded, err := schema.Load([]byte(groupsSchema))
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	decoded, err := schema.Decode([]byte(groupsSchema))
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	testCases := []struct {
+		group    string
+		expected string
+	}{
+		{group: "inline", expected: "[b i link ref frac]"},
+		{group: "linkText", expected: "[b i]"},
+		{group: "math", expected: "[frac]"},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(fmt.Sprintf("TestGroupComposition%d", i), func(t *testing.T) {
+			for _, s := range []*schema.Schema{loaded, decoded} {
+				commands, err := s.GetGroupCommands(testCase.group)
+				if err != nil {
+					t.Fatalf("Expected no error, got \"%v\"", err)
+				}
+
+				if fmt.Sprint(commands) != testCase.expected {
+					t.Errorf("Expected commands %s, got %v", testCase.expected, commands)
+				}
+			}
+		})
+	}
+}
+
+func TestGroupErrors(t *testing.T) {
+	testCases := []struct {
+		groups        string
+		expectedError error
+	}{
+		{
+			groups: `
+    - name: a
+      groups: [b]
+    - name: b
+      groups: [c]
+    - name: c
+      groups: [a]
+`,
+			expectedError: schema.ErrGroupCycle,
+		},
+		{
+			groups: `
+    - name: a
+      excludeGroups: [a]
+`,
+			expectedError: schema.ErrGroupCycle,
+		},
+		{
+			groups: `
+    - name: a
+      groups: [missing]
+`,
+			expectedError: schema.ErrGroupNotFound,
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(fmt.Sprintf("TestGroupErrors%d", i), func(t *testing.T) {
+			_, err := schema.Load([]byte("source:\n  groups:" + testCase.groups))
+			if !errors.Is(err, testCase.expectedError) {
+				t.Fatalf("Expected error \"%v\", got \"%v\"", testCase.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestCheckGroupCycle(t *testing.T) {
+	s, err := schema.Decode([]byte(`
+source:
+  allowedRootChildren: a
+  groups:
+    - name: a
+      groups: [b]
+    - name: b
+      groups: [a]
+`))
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	expected := "[5: group a: group inclusion cycle: a -> b -> a]"
+	if fmt.Sprint(s.Check()) != expected {
+		t.Errorf("Expected issues %s, got %v", expected, s.Check())
+	}
+}
@@ -0,0 +1,197 @@
+package schema
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrNoImportFS = errors.New("schema imports require LoadFS")
+var ErrImportCycle = errors.New("schema import cycle")
+var ErrImportConflict = errors.New("schema import conflict")
+
+// LoadFS loads the named schema from the file system, and merges imported
+// schemas into it before checking. Files with the .atex extension are read as
+// schemas written in atex. Definitions of the importing schema come
+// first. Imported definitions that differ from existing ones with the same
+// name are reported as conflicts, while equal ones are merged, so a schema
+// can be imported more than once.
+func LoadFS(fsys fs.FS, name string) (*Schema, error) {
+	s, err := DecodeFS(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.check()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// DecodeFS is like LoadFS, but doesn't check the schema
+func DecodeFS(fsys fs.FS, name string) (*Schema, error) {
+	return decodeFile(fsys, name, []string{}, nil)
+}
+
+func decodeFile(fsys fs.FS, name string, chain []string, issues *[]Issue) (*Schema, error) {
+	chain = append(chain, name)
+	if slices.Contains(chain[:len(chain)-1], name) {
+		return nil, fmt.Errorf("%w: %s", ErrImportCycle, strings.Join(chain, " -> "))
+	}
+
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var s *Schema
+	if path.Ext(name) == ".atex" {
+		s, err = decodeAtex(name, data, issues)
+	} else {
+		s, err = decode(name, data, issues)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	errs := []error{}
+
+	for _, i := range s.Imports {
+		imported, err := decodeFile(fsys, path.Join(path.Dir(name), i.Path), chain, issues)
+		if err != nil {
+			return nil, err
+		}
+
+		if i.Namespace != "" {
+			imported.namespace(i.Namespace)
+		}
+
+		err = s.merge(imported)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: import %s: %w", name, i.Path, err))
+		}
+	}
+
+	return s, errors.Join(errs...)
+}
+
+// namespace prefixes names of commands, groups and counters, and all
+// references to them
+func (s *Schema) namespace(namespace string) {
+	prefix := func(name string) string {
+		if name == "" {
+			return ""
+		}
+
+		return namespace + ":" + name
+	}
+
+	source := &s.Source
+	source.AllowedRootCommands = prefix(source.AllowedRootCommands)
+	source.TocCommand = prefix(source.TocCommand)
+	source.BibliographyCommand = prefix(source.BibliographyCommand)
+
+	for i := range source.Commands {
+		source.Commands[i].Command = prefix(source.Commands[i].Command)
+		source.Commands[i].Counter = prefix(source.Commands[i].Counter)
+		source.Commands[i].AllowChildren = prefix(source.Commands[i].AllowChildren)
+	}
+
+	for i := range source.Groups {
+		source.Groups[i].Name = prefix(source.Groups[i].Name)
+		for j := range source.Groups[i].Commands {
+			source.Groups[i].Commands[j] = prefix(source.Groups[i].Commands[j])
+		}
+		for _, names := range [][]string{source.Groups[i].Groups, source.Groups[i].Exclude, source.Groups[i].ExcludeGroups} {
+			for j := range names {
+				names[j] = prefix(names[j])
+			}
+		}
+		for j := range source.Groups[i].Occurrences {
+			source.Groups[i].Occurrences[j].Command = prefix(source.Groups[i].Occurrences[j].Command)
+		}
+	}
+
+	for i := range source.Counters {
+		source.Counters[i].Name = prefix(source.Counters[i].Name)
+		source.Counters[i].Within = prefix(source.Counters[i].Within)
+	}
+
+	for i := range s.Targets {
+		for j := range s.Targets[i].Commands {
+			s.Targets[i].Commands[j].Command = prefix(s.Targets[i].Commands[j].Command)
+		}
+	}
+}
+
+// merge appends definitions of the imported schema. Targets with the same
+// name are merged.
+func (s *Schema) merge(imported *Schema) error {
+	errs := []error{}
+
+	s.Source.Commands, errs = mergeNamed(s.Source.Commands, imported.Source.Commands, "command", func(c Command) string { return c.Command }, errs)
+	s.Source.Groups, errs = mergeNamed(s.Source.Groups, imported.Source.Groups, "group", func(g Group) string { return g.Name }, errs)
+	s.Source.Counters, errs = mergeNamed(s.Source.Counters, imported.Source.Counters, "counter", func(c Counter) string { return c.Name }, errs)
+
+	if s.Source.AllowedRootCommands == "" {
+		s.Source.AllowedRootCommands = imported.Source.AllowedRootCommands
+	}
+
+	if s.Source.TocCommand == "" {
+		s.Source.TocCommand = imported.Source.TocCommand
+	}
+
+	if s.Source.BibliographyCommand == "" {
+		s.Source.BibliographyCommand = imported.Source.BibliographyCommand
+	}
+
+	for _, target := range imported.Targets {
+		i := slices.IndexFunc(s.Targets, func(t Target) bool {
+			return t.Name == target.Name
+		})
+
+		if i < 0 {
+			s.Targets = append(s.Targets, target)
+			continue
+		}
+
+		fill(&s.Targets[i], target)
+		s.Targets[i].Commands, errs = mergeNamed(s.Targets[i].Commands, target.Commands, "target "+target.Name+" command", func(c TargetCommand) string { return c.Command }, errs)
+	}
+
+	return errors.Join(errs...)
+}
+
+// equalDefinitions compares definitions, ignoring their locations
+func equalDefinitions(a, b any) bool {
+	encodedA, errA := yaml.Marshal(a)
+	encodedB, errB := yaml.Marshal(b)
+
+	return errA == nil && errB == nil && bytes.Equal(encodedA, encodedB)
+}
+
+// mergeNamed appends imported items whose names are not defined, and reports
+// defined ones that differ
+func mergeNamed[T any](items, imported []T, kind string, name func(T) string, errs []error) ([]T, []error) {
+	for _, item := range imported {
+		i := slices.IndexFunc(items, func(existing T) bool {
+			return name(existing) == name(item)
+		})
+
+		if i < 0 {
+			items = append(items, item)
+		} else if !equalDefinitions(items[i], item) {
+			errs = append(errs, fmt.Errorf("%w: %s %s is already defined", ErrImportConflict, kind, name(item)))
+		}
+	}
+
+	return items, errs
+}
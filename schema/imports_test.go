@@ -0,0 +1,140 @@
+package schema_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ubavic/mint/schema"
+)
+
+var schemaFiles = fstest.MapFS{
+	"shared/core.yaml": {Data: []byte(`
+source:
+  allowedRootChildren: block
+  commands:
+    - command: p
+      arguments: 1
+    - command: b
+      arguments: 1
+  groups:
+    - name: block
+      commands: [p]
+targets:
+  - name: HTML
+    extension: html
+    escape: html
+    commands:
+      - command: p
+        expression: "<p>$1</p>"
+      - command: b
+        expression: "<b>$1</b>"
+`)},
+	"shared/math.yaml": {Data: []byte(`
+imports:
+  - path: core.yaml
+source:
+  counters:
+    - name: equation
+  commands:
+    - command: frac
+      arguments: 2
+    - command: equation
+      arguments: 1
+      counter: equation
+  groups:
+    - name: inline
+      commands: [frac]
+targets:
+  - name: HTML
+    commands:
+      - command: frac
+        expression: "<span>$1/$2</span>"
+      - command: equation
+        template: "<div>{{.Arg 1}} ({{.Number}})</div>"
+`)},
+	"paper/schema.yaml": {Data: []byte(`
+imports:
+  - path: ../shared/core.yaml
+  - path: ../shared/math.yaml
+    namespace: math
+source:
+  commands:
+    - command: abstract
+      arguments: 1
+targets:
+  - name: HTML
+    commands:
+      - command: abstract
+        expression: "<section>$1</section>"
+`)},
+	"cycle/a.yaml":    {Data: []byte("imports:\n  - path: b.yaml\n")},
+	"cycle/b.yaml":    {Data: []byte("imports:\n  - path: a.yaml\n")},
+	"conflict/a.yaml": {Data: []byte("imports:\n  - path: b.yaml\nsource:\n  commands:\n    - command: p\n      arguments: 2\n")},
+	"conflict/b.yaml": {Data: []byte("source:\n  commands:\n    - command: p\n      arguments: 1\n")},
+}
+
+func TestLoadFS(t *testing.T) {
+	s, err := schema.LoadFS(schemaFiles, "paper/schema.yaml")
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	commands := []string{}
+	for _, command := range s.Source.Commands {
+		commands = append(commands, command.Command)
+	}
+
+	expected := "[abstract p b math:frac math:equation math:p math:b]"
+	if fmt.Sprint(commands) != expected {
+		t.Errorf("Expected commands %s, got %v", expected, commands)
+	}
+
+	equation, err := s.GetCommand("math:equation")
+	if err != nil || equation.Counter != "math:equation" {
+		t.Errorf("Expected namespaced counter, got %v", equation)
+	}
+
+	group, err := s.GetGroupCommands("math:inline")
+	if err != nil || fmt.Sprint(group) != "[math:frac]" {
+		t.Errorf("Expected namespaced group, got %v", group)
+	}
+
+	if s.Source.AllowedRootCommands != "block" {
+		t.Errorf("Expected root group \"block\", got \"%s\"", s.Source.AllowedRootCommands)
+	}
+
+	html, err := s.GetTarget("HTML")
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	if html.Extension != "html" || len(html.Commands) != 7 {
+		t.Errorf("Expected merged HTML target, got %v", html)
+	}
+}
+
+func TestLoadFSErrors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		expectedError error
+	}{
+		{name: "cycle/a.yaml", expectedError: schema.ErrImportCycle},
+		{name: "conflict/a.yaml", expectedError: schema.ErrImportConflict},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(fmt.Sprintf("TestLoadFSErrors%d", i), func(t *testing.T) {
+			_, err := schema.LoadFS(schemaFiles, testCase.name)
+			if !errors.Is(err, testCase.expectedError) {
+				t.Fatalf("Expected error \"%v\", got \"%v\"", testCase.expectedError, err)
+			}
+		})
+	}
+
+	_, err := schema.Load(schemaFiles["paper/schema.yaml"].Data)
+	if !errors.Is(err, schema.ErrNoImportFS) {
+		t.Errorf("Expected error \"%v\", got \"%v\"", schema.ErrNoImportFS, err)
+	}
+}
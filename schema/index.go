@@ -0,0 +1,149 @@
+package schema
+
+import "fmt"
+
+// index replaces linear searches in loaded and compiled schemas. It is built
+// once, after the schema is checked, so such schemas must not be changed.
+// Schemas built in code are searched linearly until they are compiled.
+type index struct {
+	commands map[string]*Command
+	groups   map[string]*Group
+	// expanded are commands of groups, with included groups and exclusions
+	// resolved
+	expanded map[string][]string
+	counters map[string]*Counter
+	targets  map[string]*Target
+	root     *content
+	// children are content models of command arguments
+	children map[string]*content
+}
+
+// content is a content model of the root, or of arguments of a command
+type content struct {
+	// group is nil when any command is allowed
+	group *Group
+	// positions of allowed commands in the group
+	positions map[string]int
+	limits    map[string]Occurrence
+	text      bool
+	// err is reported for content of a command whose group is not defined
+	err error
+}
+
+// buildIndex indexes commands, groups with included groups expanded,
+// counters, targets and content models. The first definition of a name wins,
+// as in linear searches.
+func (s *Schema) buildIndex() error {
+	r := groupResolver{schema: s, expanded: map[string][]string{}}
+
+	i := &index{
+		commands: make(map[string]*Command, len(s.Source.Commands)),
+		groups:   make(map[string]*Group, len(s.Source.Groups)),
+		expanded: make(map[string][]string, len(s.Source.Groups)),
+		counters: make(map[string]*Counter, len(s.Source.Counters)),
+		targets:  make(map[string]*Target, len(s.Targets)),
+		children: make(map[string]*content, len(s.Source.Commands)),
+	}
+
+	for _, group := range s.Source.Groups {
+		if _, ok := i.groups[group.Name]; ok {
+			continue
+		}
+
+		commands, err := r.expand(group.Name, []string{})
+		if err != nil {
+			return err
+		}
+
+		i.groups[group.Name] = &group
+		i.expanded[group.Name] = commands
+	}
+
+	for _, command := range s.Source.Commands {
+		if _, ok := i.commands[command.Command]; !ok {
+			i.commands[command.Command] = &command
+		}
+	}
+
+	for _, counter := range s.Source.Counters {
+		if _, ok := i.counters[counter.Name]; !ok {
+			i.counters[counter.Name] = &counter
+		}
+	}
+
+	for _, target := range s.Targets {
+		if _, ok := i.targets[target.Name]; !ok {
+			i.targets[target.Name] = &target
+		}
+	}
+
+	s.index = i
+
+	i.root = s.newContent(s.Source.RootText, s.Source.AllowedRootCommands)
+	for name, command := range i.commands {
+		i.children[name] = s.newContent(command.Text, command.AllowChildren)
+	}
+
+	return nil
+}
+
+func (s *Schema) newContent(text *bool, groupName string) *content {
+	c := &content{text: s.AllowsText(text, groupName)}
+	if groupName == "" {
+		return c
+	}
+
+	group, err := s.GetGroup(groupName)
+	if err != nil {
+		c.err = fmt.Errorf("%w: %s", err, groupName)
+		return c
+	}
+
+	// the group of the content model holds the expanded commands, so the
+	// indexed group is copied
+	expanded := *group
+	expanded.Commands, err = s.GetGroupCommands(groupName)
+	if err != nil {
+		c.err = fmt.Errorf("%w: %s", err, groupName)
+		return c
+	}
+
+	group = &expanded
+	c.group = group
+	c.positions = make(map[string]int, len(group.Commands))
+	c.limits = make(map[string]Occurrence, len(group.Occurrences))
+
+	for position, command := range group.Commands {
+		c.positions[command] = position
+	}
+
+	for _, occurrence := range group.Occurrences {
+		c.limits[occurrence.Command] = occurrence
+	}
+
+	return c
+}
+
+// rootContent returns the content model of the root
+func (s *Schema) rootContent() *content {
+	if s.index != nil {
+		return s.index.root
+	}
+
+	return s.newContent(s.Source.RootText, s.Source.AllowedRootCommands)
+}
+
+// commandContent returns the content model of arguments of the command
+func (s *Schema) commandContent(name string) (*content, bool) {
+	if s.index != nil {
+		c, ok := s.index.children[name]
+		return c, ok
+	}
+
+	command, err := s.GetCommand(name)
+	if err != nil {
+		return nil, false
+	}
+
+	return s.newContent(command.Text, command.AllowChildren), true
+}
@@ -0,0 +1,104 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// enumFields lists values allowed in fields, or in items of list fields
+var enumFields = map[string][]string{
+	"Target.Escape":     sortedKeys(escapers),
+	"Target.Typography": sortedKeys(typographicTransforms),
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	return keys
+}
+
+// JSONSchema returns a JSON Schema of the schema format. It is generated from
+// the Go types, so editors can validate and complete schema files.
+func JSONSchema() ([]byte, error) {
+	g := jsonSchemaGenerator{definitions: map[string]any{}}
+
+	root := g.object(reflect.TypeFor[Schema]())
+	root["$schema"] = jsonSchemaDialect
+	root["title"] = "Mint schema"
+	root["$defs"] = g.definitions
+
+	return json.MarshalIndent(root, "", "  ")
+}
+
+type jsonSchemaGenerator struct {
+	definitions map[string]any
+}
+
+// object describes a struct by its yaml keys. Unknown keys are not allowed,
+// as in Load.
+func (g *jsonSchemaGenerator) object(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+
+		key, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if !field.IsExported() || key == "" || key == "-" {
+			continue
+		}
+
+		property := g.describe(field.Type)
+
+		if values, ok := enumFields[t.Name()+"."+field.Name]; ok {
+			if items, ok := property["items"].(map[string]any); ok {
+				items["enum"] = values
+			} else {
+				property["enum"] = values
+			}
+		}
+
+		properties[key] = property
+	}
+
+	return map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+func (g *jsonSchemaGenerator) describe(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.describe(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": g.describe(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.describe(t.Elem())}
+	case reflect.Struct:
+		if _, ok := g.definitions[t.Name()]; !ok {
+			// the name is reserved before fields are described, so
+			// recursive types end
+			g.definitions[t.Name()] = nil
+			g.definitions[t.Name()] = g.object(t)
+		}
+
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}
+	}
+
+	return map[string]any{}
+}
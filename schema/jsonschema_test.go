@@ -0,0 +1,144 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ubavic/mint/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// jsonSchemaValidator supports the keywords used by schema.JSONSchema
+type jsonSchemaValidator struct {
+	definitions map[string]any
+}
+
+func (v jsonSchemaValidator) validate(path string, s map[string]any, value any) []string {
+	if ref, ok := s["$ref"].(string); ok {
+		return v.validate(path, v.definitions[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any), value)
+	}
+
+	if enum, ok := s["enum"].([]any); ok && !slices.Contains(enum, value) {
+		return []string{fmt.Sprintf("%s: %v is not in %v", path, value, enum)}
+	}
+
+	errs := []string{}
+
+	switch s["type"] {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return []string{path + ": expected object"}
+		}
+
+		properties, _ := s["properties"].(map[string]any)
+		for key, item := range object {
+			property, ok := properties[key].(map[string]any)
+			if !ok {
+				property, ok = s["additionalProperties"].(map[string]any)
+			}
+
+			if !ok {
+				errs = append(errs, fmt.Sprintf("%s: unknown key %s", path, key))
+				continue
+			}
+
+			errs = append(errs, v.validate(path+"."+key, property, item)...)
+		}
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return []string{path + ": expected array"}
+		}
+
+		for i, item := range array {
+			errs = append(errs, v.validate(fmt.Sprintf("%s[%d]", path, i), s["items"].(map[string]any), item)...)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			errs = append(errs, path+": expected string")
+		}
+	case "integer":
+		if _, ok := value.(int); !ok {
+			errs = append(errs, path+": expected integer")
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			errs = append(errs, path+": expected boolean")
+		}
+	}
+
+	return errs
+}
+
+func validateJSONSchema(t *testing.T, data []byte) []string {
+	encoded, err := schema.JSONSchema()
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	jsonSchema := map[string]any{}
+	err = json.Unmarshal(encoded, &jsonSchema)
+	if err != nil {
+		t.Fatalf("Expected valid JSON, got \"%v\"", err)
+	}
+
+	var document any
+	err = yaml.Unmarshal(data, &document)
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	v := jsonSchemaValidator{definitions: jsonSchema["$defs"].(map[string]any)}
+
+	return v.validate("$", jsonSchema, document)
+}
+
+func TestJSONSchemaExample(t *testing.T) {
+	data, err := os.ReadFile("../example/schema.yaml")
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	errs := validateJSONSchema(t, data)
+	if len(errs) != 0 {
+		t.Errorf("Expected example schema to be valid, got %v", errs)
+	}
+}
+
+func TestJSONSchemaErrors(t *testing.T) {
+	testCases := []struct {
+		schema        string
+		expectedError string
+	}{
+		{
+			schema:        "source:\n  commands:\n    - command: p\n      colour: red\n",
+			expectedError: "$.source.commands[0]: unknown key colour",
+		},
+		{
+			schema:        "source:\n  commands:\n    - command: p\n      arguments: one\n",
+			expectedError: "$.source.commands[0].arguments: expected integer",
+		},
+		{
+			schema:        "targets:\n  - name: HTML\n    escape: rtf\n",
+			expectedError: "$.targets[0].escape: rtf is not in [html latex none xml]",
+		},
+		{
+			schema:        "targets:\n  - name: HTML\n    typography: [quotes, kerning]\n",
+			expectedError: "$.targets[0].typography[1]: kerning is not in [dashes ellipsis quotes]",
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(fmt.Sprintf("TestJSONSchemaErrors%d", i), func(t *testing.T) {
+			errs := validateJSONSchema(t, []byte(testCase.schema))
+			if fmt.Sprint(errs) != "["+testCase.expectedError+"]" {
+				t.Errorf("Expected error \"%s\", got %v", testCase.expectedError, errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,394 @@
+package schema
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Load unmarshals a YAML schema, resolves target inheritance, and checks
+// counters, headings, citations and targets. Unknown keys are errors.
+// Schemas in older formats are migrated, and newer ones are rejected.
+// Schemas with imports are loaded with LoadFS.
+func Load(data []byte) (*Schema, error) {
+	s, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.check()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Decode unmarshals a YAML schema without checking it. Use Check to find
+// issues in a decoded schema.
+func Decode(data []byte) (*Schema, error) {
+	s, err := decode("", data, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(s.Imports) > 0 {
+		return nil, ErrNoImportFS
+	}
+
+	return s, nil
+}
+
+// decode unmarshals the schema, and records locations of definitions.
+// Unknown keys are errors, unless issues is given, in which case they are
+// appended to it.
+func decode(file string, data []byte, issues *[]Issue) (*Schema, error) {
+	var root yaml.Node
+	err := yaml.Unmarshal(data, &root)
+	if err != nil {
+		return nil, fmt.Errorf("can't unmarshal schema: %w", err)
+	}
+
+	// migrations change values of nodes in place, so lines of definitions
+	// are those of the original text
+	_, err = migrate(&root)
+	if err != nil {
+		return nil, err
+	}
+
+	// migrations don't rename keys, so unknown keys are found in the
+	// original text
+	err = decodeStrict(data, &Schema{})
+
+	var typeError *yaml.TypeError
+	if issues != nil && errors.As(err, &typeError) {
+		for _, message := range typeError.Errors {
+			*issues = append(*issues, unknownKeyIssue(file, message))
+		}
+
+		err = nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("can't unmarshal schema: %w", err)
+	}
+
+	var s Schema
+
+	if len(root.Content) > 0 {
+		err = root.Decode(&s)
+		if err != nil {
+			return nil, fmt.Errorf("can't unmarshal schema: %w", err)
+		}
+	}
+
+	s.locate(file, &root)
+
+	return &s, nil
+}
+
+func decodeStrict(data []byte, s *Schema) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	err := decoder.Decode(s)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+
+	return err
+}
+
+// Compile checks a schema built in code like Load checks loaded schemas, and
+// indexes it, so commands, groups, counters and targets are looked up by name.
+// The schema must not be changed after it is compiled.
+func (s *Schema) Compile() error {
+	return s.check()
+}
+
+func (s *Schema) check() error {
+	// checks search the schema as it is, in case it was indexed before
+	s.index = nil
+
+	err := errors.Join(s.resolveTargets(), s.checkCounters(), s.checkHeadings(), s.checkCitations(), s.resolveGroups(), s.checkOccurrences(), s.checkTargets())
+	if err != nil {
+		return err
+	}
+
+	return s.buildIndex()
+}
+
+// checkTargets checks text transforms of targets, parses document templates
+// and all target expressions and templates, and checks them against the
+// number of arguments of source commands
+func (s *Schema) checkTargets() error {
+	errs := []error{}
+
+	for _, target := range s.Targets {
+		errs = append(errs, s.checkTarget(target))
+		errs = append(errs, s.checkTargetCommands(target)...)
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkTarget checks the text transform and templates of the target
+func (s *Schema) checkTarget(target Target) error {
+	errs := []error{}
+
+	_, err := target.TextTransform()
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	if target.Toc != "" {
+		_, err := ParseTocTemplate(target.Name, target.Toc)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("target %s, toc: %w", target.Name, err))
+		}
+	}
+
+	if target.Bibliography != "" {
+		_, err := ParseBibliographyTemplate(target.Name, target.Bibliography)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("target %s, bibliography: %w", target.Name, err))
+		}
+	}
+
+	if target.Document != "" {
+		_, err := ParseDocumentTemplate(target.Name, target.Document)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("target %s, document: %w", target.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkTargetCommands returns an error, or nil, for each command of the
+// target
+func (s *Schema) checkTargetCommands(target Target) []error {
+	buckets := []string{}
+	for _, targetCommand := range target.Commands {
+		if targetCommand.Collect != nil {
+			buckets = append(buckets, targetCommand.Collect.Bucket)
+		}
+	}
+
+	errs := make([]error, len(target.Commands))
+
+	for i, targetCommand := range target.Commands {
+		err := s.checkTargetCommand(targetCommand)
+		if err == nil {
+			err = s.checkBuckets(targetCommand, buckets)
+		}
+
+		if err != nil {
+			errs[i] = fmt.Errorf("target %s, command %s: %w", target.Name, targetCommand.Command, err)
+		}
+	}
+
+	return errs
+}
+
+var ErrInvalidBucket = errors.New("invalid bucket")
+
+// checkBuckets checks that collecting commands have templates, and that
+// flushed buckets are collected by some command of the target
+func (s *Schema) checkBuckets(targetCommand TargetCommand, buckets []string) error {
+	if targetCommand.Flush != "" {
+		if !slices.Contains(buckets, targetCommand.Flush) {
+			return fmt.Errorf("%w: %s is not collected", ErrInvalidBucket, targetCommand.Flush)
+		}
+
+		if targetCommand.Expression != "" {
+			return fmt.Errorf("%w: flush requires a template instead of an expression", ErrInvalidBucket)
+		}
+	}
+
+	if targetCommand.Collect == nil {
+		return nil
+	}
+
+	if targetCommand.Collect.Bucket == "" {
+		return fmt.Errorf("%w: missing bucket name", ErrInvalidBucket)
+	}
+
+	if targetCommand.Template == "" {
+		return fmt.Errorf("%w: collect requires a template", ErrInvalidBucket)
+	}
+
+	arguments, variadic := 0, true
+
+	command, err := s.GetCommand(targetCommand.Command)
+	if err == nil {
+		arguments, variadic = command.Arguments, command.Variadic
+	}
+
+	_, err = ParseTemplate(targetCommand.Command, targetCommand.Collect.Template, arguments, variadic)
+
+	return err
+}
+
+func (s *Schema) checkTargetCommand(targetCommand TargetCommand) error {
+	arguments, variadic := 0, true
+
+	command, err := s.GetCommand(targetCommand.Command)
+	if err == nil {
+		arguments, variadic = command.Arguments, command.Variadic
+	}
+
+	if targetCommand.Template != "" {
+		if targetCommand.Expression != "" {
+			return fmt.Errorf("%w: both expression and template are set", ErrInvalidTemplate)
+		}
+
+		_, err := ParseTemplate(targetCommand.Command, targetCommand.Template, arguments, variadic)
+		return err
+	}
+
+	expression, err := ParseExpression(targetCommand.Expression)
+	if err != nil {
+		return err
+	}
+
+	if !variadic && expression.MaxArgument() > arguments {
+		return fmt.Errorf("%w: placeholder $%d exceeds %d arguments", ErrInvalidExpression, expression.MaxArgument(), arguments)
+	}
+
+	if expression.Numbered() && command != nil && command.Counter == "" {
+		return fmt.Errorf("%w: placeholder ${#} of a command without a counter", ErrInvalidExpression)
+	}
+
+	return nil
+}
+
+var ErrInvalidCounter = errors.New("invalid counter")
+
+// checkCounters checks that counters used by commands exist, and that
+// counters are not nested within themselves
+func (s *Schema) checkCounters() error {
+	errs := []error{}
+
+	for _, command := range s.Source.Commands {
+		if command.Counter != "" {
+			if _, err := s.GetCounter(command.Counter); err != nil {
+				errs = append(errs, fmt.Errorf("command %s: %w: %s", command.Command, err, command.Counter))
+			}
+		}
+
+		if command.Label < 0 || (command.Label > command.Arguments && !command.Variadic) {
+			errs = append(errs, fmt.Errorf("command %s: %w: label argument %d exceeds %d arguments", command.Command, ErrInvalidCounter, command.Label, command.Arguments))
+		}
+
+		if command.Label != 0 && command.Counter == "" {
+			errs = append(errs, fmt.Errorf("command %s: %w: label without a counter", command.Command, ErrInvalidCounter))
+		}
+	}
+
+	for _, counter := range s.Source.Counters {
+		visited := []string{counter.Name}
+		within := counter.Within
+
+		for within != "" {
+			parent, err := s.GetCounter(within)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("counter %s: %w: %s", counter.Name, err, within))
+				break
+			}
+
+			if slices.Contains(visited, parent.Name) {
+				errs = append(errs, fmt.Errorf("counter %s: %w: cycle %s", counter.Name, ErrInvalidCounter, strings.Join(append(visited, parent.Name), " -> ")))
+				break
+			}
+
+			visited = append(visited, parent.Name)
+			within = parent.Within
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+var ErrInvalidHeading = errors.New("invalid heading")
+
+// checkHeadings checks heading levels and title arguments, and the table of
+// contents command
+func (s *Schema) checkHeadings() error {
+	errs := []error{}
+
+	for _, command := range s.Source.Commands {
+		if command.Heading == nil {
+			continue
+		}
+
+		if command.Heading.Level < 1 {
+			errs = append(errs, fmt.Errorf("command %s: %w: level must be positive", command.Command, ErrInvalidHeading))
+		}
+
+		if command.Heading.Title < 1 || (command.Heading.Title > command.Arguments && !command.Variadic) {
+			errs = append(errs, fmt.Errorf("command %s: %w: title argument %d is not one of %d arguments", command.Command, ErrInvalidHeading, command.Heading.Title, command.Arguments))
+		}
+	}
+
+	if s.Source.TocCommand != "" {
+		if _, err := s.GetCommand(s.Source.TocCommand); err != nil {
+			errs = append(errs, fmt.Errorf("toc command %s: %w", s.Source.TocCommand, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+var ErrInvalidOccurrence = errors.New("invalid occurrence")
+
+// checkOccurrences checks that occurrences of groups refer to commands of the
+// group, and that their limits are consistent
+func (s *Schema) checkOccurrences() error {
+	errs := []error{}
+
+	for _, group := range s.Source.Groups {
+		commands, err := s.GetGroupCommands(group.Name)
+		if err != nil {
+			continue
+		}
+
+		for _, occurrence := range group.Occurrences {
+			if !slices.Contains(commands, occurrence.Command) {
+				errs = append(errs, fmt.Errorf("group %s: %w: command %s is not in the group", group.Name, ErrInvalidOccurrence, occurrence.Command))
+			}
+
+			if occurrence.Min < 0 || occurrence.Max < 0 || (occurrence.Max > 0 && occurrence.Min > occurrence.Max) {
+				errs = append(errs, fmt.Errorf("group %s: %w: command %s has limits %d to %d", group.Name, ErrInvalidOccurrence, occurrence.Command, occurrence.Min, occurrence.Max))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+var ErrInvalidCitation = errors.New("invalid citation")
+
+// checkCitations checks cite arguments, and the bibliography command
+func (s *Schema) checkCitations() error {
+	errs := []error{}
+
+	for _, command := range s.Source.Commands {
+		if command.Cite < 0 || (command.Cite > command.Arguments && !command.Variadic) {
+			errs = append(errs, fmt.Errorf("command %s: %w: cite argument %d exceeds %d arguments", command.Command, ErrInvalidCitation, command.Cite, command.Arguments))
+		}
+	}
+
+	if s.Source.BibliographyCommand != "" {
+		if _, err := s.GetCommand(s.Source.BibliographyCommand); err != nil {
+			errs = append(errs, fmt.Errorf("bibliography command %s: %w", s.Source.BibliographyCommand, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
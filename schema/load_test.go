@@ -0,0 +1,437 @@
+package schema_test
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ubavic/mint/parser"
+	"github.com/ubavic/mint/schema"
+)
+
+func TestLoadChecksCounters(t *testing.T) {
+	testCases := []struct {
+		schema        string
+		expectedError error
+	}{
+		{
+			schema: `
+source:
+  counters:
+    - name: section
+    - name: subsection
+      within: section
+  commands:
+    - command: section
+      arguments: 2
+      counter: section
+      label: 2
+`,
+		},
+		{
+			schema: `
+source:
+  commands:
+    - command: section
+      arguments: 1
+      counter: section
+`,
+			expectedError: schema.ErrCounterNotFound,
+		},
+		{
+			schema: `
+source:
+  counters:
+    - name: section
+      within: chapter
+`,
+			expectedError: schema.ErrCounterNotFound,
+		},
+		{
+			schema: `
+source:
+  counters:
+    - name: a
+      within: b
+    - name: b
+      within: a
+`,
+			expectedError: schema.ErrInvalidCounter,
+		},
+		{
+			schema: `
+source:
+  counters:
+    - name: figure
+  commands:
+    - command: figure
+      arguments: 1
+      counter: figure
+      label: 2
+`,
+			expectedError: schema.ErrInvalidCounter,
+		},
+		{
+			schema: `
+source:
+  commands:
+    - command: figure
+      arguments: 1
+      label: 1
+`,
+			expectedError: schema.ErrInvalidCounter,
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(
+			fmt.Sprintf("TestLoadChecksCounters%d", i),
+			func(t *testing.T) {
+				_, err := schema.Load([]byte(testCase.schema))
+				if !errors.Is(err, testCase.expectedError) {
+					t.Fatalf("Expected error \"%v\", got \"%v\"", testCase.expectedError, err)
+				}
+			},
+		)
+	}
+}
+
+func TestLoadChecksHeadings(t *testing.T) {
+	testCases := []struct {
+		schema        string
+		expectedError error
+	}{
+		{
+			schema: `
+source:
+  tocCommand: toc
+  commands:
+    - command: toc
+    - command: section
+      arguments: 2
+      heading:
+        level: 1
+        title: 2
+targets:
+  - name: HTML
+    toc: "{{range .Entries}}{{.Title}}{{end}}"
+`,
+		},
+		{
+			schema: `
+source:
+  tocCommand: contents
+`,
+			expectedError: schema.ErrCommandNotFound,
+		},
+		{
+			schema: `
+source:
+  commands:
+    - command: section
+      arguments: 1
+      heading:
+        level: 1
+        title: 2
+`,
+			expectedError: schema.ErrInvalidHeading,
+		},
+		{
+			schema: `
+source:
+  commands:
+    - command: section
+      arguments: 1
+      heading:
+        title: 1
+`,
+			expectedError: schema.ErrInvalidHeading,
+		},
+		{
+			schema: `
+targets:
+  - name: HTML
+    toc: "{{.Body}}"
+`,
+			expectedError: schema.ErrInvalidTemplate,
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(
+			fmt.Sprintf("TestLoadChecksHeadings%d", i),
+			func(t *testing.T) {
+				_, err := schema.Load([]byte(testCase.schema))
+				if !errors.Is(err, testCase.expectedError) {
+					t.Fatalf("Expected error \"%v\", got \"%v\"", testCase.expectedError, err)
+				}
+			},
+		)
+	}
+}
+
+func TestLoadChecksBuckets(t *testing.T) {
+	testCases := []struct {
+		schema        string
+		expectedError error
+	}{
+		{
+			schema: `
+source:
+  commands:
+    - command: footnote
+      arguments: 1
+    - command: notes
+targets:
+  - name: HTML
+    commands:
+      - command: footnote
+        template: "<sup>{{.Index}}</sup>"
+        collect:
+          bucket: notes
+          template: "<li>{{.Arg 1}}</li>"
+      - command: notes
+        flush: notes
+        template: "<ol>{{range .Items}}{{.}}{{end}}</ol>"
+`,
+		},
+		{
+			schema: `
+targets:
+  - name: HTML
+    commands:
+      - command: notes
+        flush: notes
+`,
+			expectedError: schema.ErrInvalidBucket,
+		},
+		{
+			schema: `
+source:
+  commands:
+    - command: footnote
+      arguments: 1
+targets:
+  - name: HTML
+    commands:
+      - command: footnote
+        expression: "<sup>*</sup>"
+        collect:
+          bucket: notes
+          template: "<li>{{.Arg 1}}</li>"
+`,
+			expectedError: schema.ErrInvalidBucket,
+		},
+		{
+			schema: `
+source:
+  commands:
+    - command: footnote
+      arguments: 1
+targets:
+  - name: HTML
+    commands:
+      - command: footnote
+        template: "<sup>{{.Index}}</sup>"
+        collect:
+          bucket: notes
+          template: "<li>{{.Arg 2}}</li>"
+`,
+			expectedError: schema.ErrInvalidTemplate,
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(
+			fmt.Sprintf("TestLoadChecksBuckets%d", i),
+			func(t *testing.T) {
+				_, err := schema.Load([]byte(testCase.schema))
+				if !errors.Is(err, testCase.expectedError) {
+					t.Fatalf("Expected error \"%v\", got \"%v\"", testCase.expectedError, err)
+				}
+			},
+		)
+	}
+}
+
+func TestLoadChecksCitations(t *testing.T) {
+	testCases := []struct {
+		schema        string
+		expectedError error
+	}{
+		{
+			schema: `
+source:
+  bibliographyCommand: references
+  commands:
+    - command: cite
+      arguments: 1
+      cite: 1
+    - command: references
+targets:
+  - name: HTML
+    bibliography: "{{range .References}}{{.Fields.title}}{{end}}"
+    commands:
+      - command: cite
+        template: "{{range .Citations}}{{.Number}}{{end}}"
+`,
+		},
+		{
+			schema: `
+source:
+  commands:
+    - command: cite
+      arguments: 1
+      cite: 2
+`,
+			expectedError: schema.ErrInvalidCitation,
+		},
+		{
+			schema: `
+source:
+  bibliographyCommand: references
+`,
+			expectedError: schema.ErrCommandNotFound,
+		},
+		{
+			schema: `
+targets:
+  - name: HTML
+    bibliography: "{{.Entries}}"
+`,
+			expectedError: schema.ErrInvalidTemplate,
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(
+			fmt.Sprintf("TestLoadChecksCitations%d", i),
+			func(t *testing.T) {
+				_, err := schema.Load([]byte(testCase.schema))
+				if !errors.Is(err, testCase.expectedError) {
+					t.Fatalf("Expected error \"%v\", got \"%v\"", testCase.expectedError, err)
+				}
+			},
+		)
+	}
+}
+
+func TestLoadChecksOccurrences(t *testing.T) {
+	testCases := []struct {
+		schema        string
+		expectedError error
+	}{
+		{
+			schema: `
+source:
+  groups:
+    - name: figure
+      commands: [image, caption]
+      occurrences:
+        - command: caption
+          min: 1
+          max: 1
+`,
+		},
+		{
+			schema: `
+source:
+  groups:
+    - name: figure
+      commands: [image]
+      occurrences:
+        - command: caption
+          min: 1
+`,
+			expectedError: schema.ErrInvalidOccurrence,
+		},
+		{
+			schema: `
+source:
+  groups:
+    - name: figure
+      commands: [caption]
+      occurrences:
+        - command: caption
+          min: 2
+          max: 1
+`,
+			expectedError: schema.ErrInvalidOccurrence,
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(
+			fmt.Sprintf("TestLoadChecksOccurrences%d", i),
+			func(t *testing.T) {
+				_, err := schema.Load([]byte(testCase.schema))
+				if !errors.Is(err, testCase.expectedError) {
+					t.Fatalf("Expected error \"%v\", got \"%v\"", testCase.expectedError, err)
+				}
+			},
+		)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	testCases := []struct {
+		schema        schema.Schema
+		input         string
+		expectedError error
+	}{
+		{
+			schema: schema.Schema{Source: schema.Source{
+				Commands: []schema.Command{{Command: "section", Arguments: 1, Counter: "section"}},
+			}},
+			expectedError: schema.ErrCounterNotFound,
+		},
+		{
+			schema: schema.Schema{Source: schema.Source{
+				AllowedRootCommands: "blocks",
+				Commands:            []schema.Command{{Command: "p", Arguments: 1}, {Command: "b", Arguments: 1}},
+				Groups:              []schema.Group{{Name: "blocks", Commands: []string{"p"}}},
+			}},
+			input: "@p{@b{x}}",
+		},
+		{
+			schema: schema.Schema{Source: schema.Source{
+				AllowedRootCommands: "blocks",
+				Commands:            []schema.Command{{Command: "p", Arguments: 1}, {Command: "b", Arguments: 1}},
+				Groups:              []schema.Group{{Name: "blocks", Commands: []string{"p"}}},
+			}},
+			input:         "@b{x}",
+			expectedError: schema.ErrCommandNotAllowed,
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(fmt.Sprintf("TestCompile%d", i), func(t *testing.T) {
+			s := testCase.schema
+
+			err := s.Compile()
+			if testCase.input == "" {
+				if !errors.Is(err, testCase.expectedError) {
+					t.Fatalf("Expected error \"%v\", got \"%v\"", testCase.expectedError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error, got \"%v\"", err)
+			}
+
+			// compiled schemas validate like schemas searched linearly
+			for _, validator := range []*schema.Schema{&s, &testCase.schema} {
+				tokenizer := parser.NewTokenizer(bufio.NewReader(strings.NewReader(testCase.input)))
+				p := parser.NewParser(tokenizer.Tokenize(), validator)
+
+				_, err = p.Parse()
+				if !errors.Is(err, testCase.expectedError) {
+					t.Errorf("Expected error \"%v\", got \"%v\"", testCase.expectedError, err)
+				}
+			}
+		})
+	}
+}
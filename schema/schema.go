@@ -1,35 +1,164 @@
 package schema
 
 type Schema struct {
-	Mint    string   `yaml:"mint"`
-	Name    string   `yaml:"name"`
-	Version string   `yaml:"version"`
+	Mint    string `yaml:"mint"`
+	Name    string `yaml:"name"`
+	Author  string `yaml:"author"`
+	Version string `yaml:"version"`
+	// Imports are merged into the schema when it is loaded with LoadFS
+	Imports []Import `yaml:"imports"`
 	Source  Source   `yaml:"source"`
 	Targets []Target `yaml:"targets"`
+
+	// index is built when the schema is loaded
+	index *index
+}
+
+type Import struct {
+	// Path is relative to the importing schema
+	Path string `yaml:"path"`
+	// Namespace prefixes names of imported commands, groups and counters,
+	// like `math:frac`
+	Namespace string `yaml:"namespace"`
 }
 
 type Command struct {
 	Command     string `yaml:"command"`
 	Arguments   int    `yaml:"arguments"`
 	Description string `yaml:"description"`
+	// AllowChildren is a group of commands allowed in arguments
+	AllowChildren string `yaml:"allowChildren"`
+	// Text allows non-whitespace text in arguments. When unset, the Text of
+	// the AllowChildren group applies, and text is allowed without one.
+	Text *bool `yaml:"text"`
+	// Variadic commands accept any number of arguments beyond Arguments
+	Variadic bool `yaml:"variadic"`
+	// Counter is incremented each time the command appears
+	Counter string `yaml:"counter"`
+	// Label is the index of the argument whose text labels the command for
+	// cross-references, or zero. Only commands with a Counter have labels.
+	Label int `yaml:"label"`
+	// Cite is the index of the argument holding comma separated keys of
+	// cited bibliography entries, or zero
+	Cite int `yaml:"cite"`
+	// Heading marks the command as an entry of the document outline
+	Heading *Heading `yaml:"heading"`
+	// Include marks a command whose only argument is a name of a file that
+	// replaces the command
+	Include bool `yaml:"include"`
+
+	location location
 }
 
 type Target struct {
-	Name      string `yaml:"name"`
+	Name string `yaml:"name"`
+	// Extends is the name of a target whose commands, escaping and templates
+	// are inherited. Commands of this target override inherited ones.
+	Extends   string `yaml:"extends"`
 	Extension string `yaml:"extension"`
-	Commands  []struct {
-		Command    string `yaml:"command"`
-		Expression string `yaml:"expression"`
-	} `yaml:"commands"`
+	// Escape selects a built-in escaper for text: html, xml, latex or none
+	Escape string `yaml:"escape"`
+	// EscapeMap replaces characters in text, and takes precedence over the
+	// built-in escaper
+	EscapeMap map[string]string `yaml:"escapeMap"`
+	// Typography lists transforms applied to text before escaping: quotes,
+	// dashes and ellipsis
+	Typography []string `yaml:"typography"`
+	// Document is a template that wraps the rendered document
+	Document string `yaml:"document"`
+	// Toc is a template of the table of contents
+	Toc string `yaml:"toc"`
+	// Bibliography is a template of the list of cited references
+	Bibliography string          `yaml:"bibliography"`
+	Commands     []TargetCommand `yaml:"commands"`
+
+	location location
+}
+
+type TargetCommand struct {
+	Command    string `yaml:"command"`
+	Expression string `yaml:"expression"`
+	// Template is used instead of Expression when set
+	Template string `yaml:"template"`
+	// Packages are made available to the document template when the command
+	// is rendered
+	Packages []string `yaml:"packages"`
+	// Collect pushes content into a bucket, to be written later. It requires
+	// Template.
+	Collect *Collect `yaml:"collect"`
+	// Flush is a bucket whose items are written in place of the command and
+	// removed. A template receives them as .Items.
+	Flush string `yaml:"flush"`
+
+	location location
+}
+
+type Collect struct {
+	Bucket string `yaml:"bucket"`
+	// Template renders the item pushed into the bucket, with the same data
+	// as the command template
+	Template string `yaml:"template"`
 }
 
 type Source struct {
-	AllowedRootCommands string    `yaml:"allowedRootChildren"`
+	AllowedRootCommands string `yaml:"allowedRootChildren"`
+	// RootText allows non-whitespace text at the root. When unset, the Text of
+	// the root group applies.
+	RootText *bool `yaml:"rootText"`
+	// TocCommand is replaced with the table of contents
+	TocCommand string `yaml:"tocCommand"`
+	// BibliographyCommand is replaced with the list of cited references
+	BibliographyCommand string    `yaml:"bibliographyCommand"`
 	Commands            []Command `yaml:"commands"`
 	Groups              []Group   `yaml:"groups"`
+	Counters            []Counter `yaml:"counters"`
+}
+
+type Heading struct {
+	// Level of the heading, starting from 1
+	Level int `yaml:"level"`
+	// Title is the index of the argument holding the title
+	Title int `yaml:"title"`
 }
 
+type Counter struct {
+	Name string `yaml:"name"`
+	// Within is a counter that resets this one when incremented. Numbers of
+	// the counter are prefixed with the number of the Within counter.
+	Within string `yaml:"within"`
+
+	location location
+}
+
+// Group is a content model: commands allowed at the root or in arguments of
+// a command, with optional limits of their occurrences and order
 type Group struct {
 	Name     string   `yaml:"name"`
 	Commands []string `yaml:"commands"`
+	// Groups are included groups, whose commands are added to Commands
+	Groups []string `yaml:"groups"`
+	// Exclude removes commands from the group, after groups are included
+	Exclude []string `yaml:"exclude"`
+	// ExcludeGroups removes commands of the groups, like links from inline
+	// content of link text
+	ExcludeGroups []string `yaml:"excludeGroups"`
+	// Occurrences limit how many times commands of the group appear at the
+	// root, or in each argument
+	Occurrences []Occurrence `yaml:"occurrences"`
+	// Ordered requires commands to appear in the order of Commands
+	Ordered bool `yaml:"ordered"`
+	// Text allows non-whitespace text where the group applies. When unset,
+	// text is allowed.
+	Text *bool `yaml:"text"`
+
+	location location
+}
+
+type Occurrence struct {
+	Command string `yaml:"command"`
+	// Min is the least number of occurrences. Commands with positive Min are
+	// required.
+	Min int `yaml:"min"`
+	// Max is the greatest number of occurrences, or zero for no limit
+	Max int `yaml:"max"`
 }
@@ -0,0 +1,398 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"unicode"
+)
+
+var ErrInvalidTemplate = errors.New("invalid template")
+
+// Template is a target command or document template written in text/template
+// syntax. The dot is a *CommandContext or a *DocumentContext. Functions
+// `upper`, `lower`, `trim` and `slug` are available, as well as `url` and
+// `escape` for escaping plain text, like `{{escape "latex" (.Text 1)}}`.
+type Template struct {
+	template *template.Template
+}
+
+var templateFunctions = template.FuncMap{
+	"upper":  strings.ToUpper,
+	"lower":  strings.ToLower,
+	"trim":   strings.TrimSpace,
+	"slug":   Slug,
+	"url":    EscapeURL,
+	"escape": EscapeArgument,
+}
+
+// ParseTemplate parses the template, and checks that it refers only to
+// existing fields of CommandContext and to declared arguments. Variadic
+// commands may refer to arguments past the declared number.
+func ParseTemplate(name, text string, arguments int, variadic bool) (*Template, error) {
+	return parseTemplate(name, text, templateChecker{dataType: commandContextType, arguments: arguments, variadic: variadic})
+}
+
+// ParseTocTemplate parses a target table of contents template, and checks
+// that it refers only to existing fields of TocContext
+func ParseTocTemplate(name, text string) (*Template, error) {
+	return parseTemplate(name, text, templateChecker{dataType: tocContextType})
+}
+
+// ParseBibliographyTemplate parses a target bibliography template, and checks
+// that it refers only to existing fields of BibliographyContext
+func ParseBibliographyTemplate(name, text string) (*Template, error) {
+	return parseTemplate(name, text, templateChecker{dataType: bibliographyContextType})
+}
+
+// ParseDocumentTemplate parses a target document template, and checks that
+// it refers only to existing fields of DocumentContext
+func ParseDocumentTemplate(name, text string) (*Template, error) {
+	return parseTemplate(name, text, templateChecker{dataType: documentContextType})
+}
+
+func parseTemplate(name, text string, checker templateChecker) (*Template, error) {
+	t, err := template.New(name).Option("missingkey=zero").Funcs(templateFunctions).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+	}
+
+	if t.Tree != nil {
+		checker.checkNode(t.Tree.Root)
+	}
+
+	if checker.err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalidTemplate, name, checker.err)
+	}
+
+	return &Template{template: t}, nil
+}
+
+// Execute executes the template with a *CommandContext, *TocContext or a
+// *DocumentContext
+func (t *Template) Execute(w io.Writer, data any) error {
+	return t.template.Execute(w, data)
+}
+
+// CommandContext is the data available to command templates
+type CommandContext struct {
+	// Name of the command
+	Name string
+	// Parent is the name of the enclosing command, or empty at the root
+	Parent string
+	// Meta holds document metadata
+	Meta map[string]string
+	// Number is the number assigned to the command by its counter, like
+	// "3.2", or empty
+	Number string
+	// Anchor is a unique identifier of a heading, or empty
+	Anchor string
+	// Index is the number of the item pushed into a bucket by the command,
+	// counted from 1 in the whole document
+	Index int
+	// Items are removed from the bucket flushed by the command
+	Items []string
+	// Citations are references cited by the command, in the order of keys
+	Citations []*Reference
+
+	arguments int
+	render    func(i int) (string, error)
+	text      func(i int) string
+	rendered  map[int]string
+	counter   func(name string) int
+	ref       func(label string) (string, error)
+}
+
+// NewCommandContext creates a template context for a command with the given
+// number of arguments. render renders the i-th argument, and text returns its
+// plain text. Arguments are counted from 1.
+func NewCommandContext(name, parent string, meta map[string]string, arguments int, render func(i int) (string, error), text func(i int) string) *CommandContext {
+	return &CommandContext{
+		Name:      name,
+		Parent:    parent,
+		Meta:      meta,
+		arguments: arguments,
+		render:    render,
+		text:      text,
+		rendered:  map[int]string{},
+	}
+}
+
+// SetNumbering sets the number of the command, and functions returning
+// values of counters at the command and numbers of labeled commands
+func (c *CommandContext) SetNumbering(number string, counter func(name string) int, ref func(label string) (string, error)) {
+	c.Number = number
+	c.counter = counter
+	c.ref = ref
+}
+
+// Counter returns the value of the counter at the command
+func (c *CommandContext) Counter(name string) int {
+	if c.counter == nil {
+		return 0
+	}
+
+	return c.counter(name)
+}
+
+// Ref returns the number of the command labeled with label
+func (c *CommandContext) Ref(label string) (string, error) {
+	if c.ref == nil {
+		return "", fmt.Errorf("reference %s can't be resolved without numbering", label)
+	}
+
+	return c.ref(label)
+}
+
+// Arg returns the i-th rendered argument
+func (c *CommandContext) Arg(i int) (string, error) {
+	if i < 1 || i > c.arguments {
+		return "", fmt.Errorf("command %s has no argument %d", c.Name, i)
+	}
+
+	if result, ok := c.rendered[i]; ok {
+		return result, nil
+	}
+
+	result, err := c.render(i)
+	if err != nil {
+		return "", err
+	}
+
+	c.rendered[i] = result
+
+	return result, nil
+}
+
+// Args returns all rendered arguments
+func (c *CommandContext) Args() ([]string, error) {
+	result := make([]string, c.arguments)
+
+	for i := range result {
+		arg, err := c.Arg(i + 1)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = arg
+	}
+
+	return result, nil
+}
+
+// Text returns the plain text of the i-th argument, without commands
+func (c *CommandContext) Text(i int) (string, error) {
+	if i < 1 || i > c.arguments {
+		return "", fmt.Errorf("command %s has no argument %d", c.Name, i)
+	}
+
+	return c.text(i), nil
+}
+
+// DocumentContext is the data available to document templates
+type DocumentContext struct {
+	// Body is the rendered document
+	Body string
+	// Meta holds document metadata
+	Meta map[string]string
+	// Packages lists packages required by rendered commands, in order of
+	// first use
+	Packages []string
+	// Toc is the rendered table of contents
+	Toc string
+	// Bibliography is the rendered list of cited references
+	Bibliography string
+	// Outline holds top level headings of the document
+	Outline []*OutlineEntry
+	// Collected holds items of buckets that weren't flushed by commands
+	Collected map[string][]string
+}
+
+var documentContextType = reflect.TypeOf(&DocumentContext{})
+
+// TocContext is the data available to table of contents templates. Use
+// `{{define}}` and `{{template}}` to render nested entries.
+type TocContext struct {
+	// Entries are top level headings of the document
+	Entries []*OutlineEntry
+	// Meta holds document metadata
+	Meta map[string]string
+}
+
+var tocContextType = reflect.TypeOf(&TocContext{})
+
+// OutlineEntry is a heading in the document outline
+type OutlineEntry struct {
+	// Level of the heading as declared in the schema
+	Level int
+	// Command is the name of the heading command
+	Command string
+	// Title is the rendered title
+	Title string
+	// Text is the plain text of the title
+	Text string
+	// Number is the number assigned to the heading by its counter, or empty
+	Number string
+	// Anchor is a unique identifier derived from the title
+	Anchor   string
+	Children []*OutlineEntry
+}
+
+// BibliographyContext is the data available to bibliography templates
+type BibliographyContext struct {
+	// References are cited references in the order of first citation
+	References []*Reference
+	// Meta holds document metadata
+	Meta map[string]string
+}
+
+var bibliographyContextType = reflect.TypeOf(&BibliographyContext{})
+
+// Reference is a cited bibliography entry
+type Reference struct {
+	Key string
+	// Type is the entry type, like "article"
+	Type string
+	// Number is the order of the first citation, counted from 1, or zero
+	// for undefined keys
+	Number int
+	// Fields hold lowercase field names, like .Fields.title
+	Fields map[string]string
+}
+
+// Slug converts text to a lowercase identifier with words separated by `-`
+func Slug(text string) string {
+	var builder strings.Builder
+	dash := false
+
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && builder.Len() > 0 {
+				builder.WriteRune('-')
+			}
+			builder.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+
+	return builder.String()
+}
+
+type templateChecker struct {
+	dataType  reflect.Type
+	arguments int
+	variadic  bool
+	// insideRange is true when the dot is not the template data
+	insideRange bool
+	err         error
+}
+
+var commandContextType = reflect.TypeOf(&CommandContext{})
+
+// argumentMethods take an argument index as the first parameter
+var argumentMethods = []string{"Arg", "Text"}
+
+func (c *templateChecker) checkNode(node parse.Node) {
+	if c.err != nil || node == nil || reflect.ValueOf(node).IsNil() {
+		return
+	}
+
+	switch n := node.(type) {
+	case *parse.ListNode:
+		for _, child := range n.Nodes {
+			c.checkNode(child)
+		}
+	case *parse.ActionNode:
+		c.checkNode(n.Pipe)
+	case *parse.IfNode:
+		c.checkNode(n.Pipe)
+		c.checkNode(n.List)
+		c.checkNode(n.ElseList)
+	case *parse.RangeNode:
+		c.checkRange(&n.BranchNode)
+	case *parse.WithNode:
+		c.checkRange(&n.BranchNode)
+	case *parse.PipeNode:
+		for _, command := range n.Cmds {
+			c.checkNode(command)
+		}
+	case *parse.CommandNode:
+		for i, arg := range n.Args {
+			switch first := arg.(type) {
+			case *parse.FieldNode:
+				if i == 0 && !c.insideRange {
+					c.checkField(first.Ident, n.Args[1:])
+					continue
+				}
+			case *parse.VariableNode:
+				if i == 0 && first.Ident[0] == "$" && len(first.Ident) > 1 {
+					c.checkField(first.Ident[1:], n.Args[1:])
+					continue
+				}
+			}
+
+			c.checkNode(arg)
+		}
+	case *parse.FieldNode:
+		if !c.insideRange {
+			c.checkField(n.Ident, nil)
+		}
+	case *parse.VariableNode:
+		if n.Ident[0] == "$" && len(n.Ident) > 1 {
+			c.checkField(n.Ident[1:], nil)
+		}
+	}
+}
+
+// checkRange checks `range` and `with` blocks, where the dot is changed. Use
+// `$` to refer to the template data inside them.
+func (c *templateChecker) checkRange(n *parse.BranchNode) {
+	c.checkNode(n.Pipe)
+	c.checkNode(n.ElseList)
+
+	insideRange := c.insideRange
+	c.insideRange = true
+	c.checkNode(n.List)
+	c.insideRange = insideRange
+}
+
+// checkField checks the first identifier of a field chain, and literal
+// argument indices
+func (c *templateChecker) checkField(ident []string, args []parse.Node) {
+	name := ident[0]
+
+	field, isField := c.dataType.Elem().FieldByName(name)
+	_, isMethod := c.dataType.MethodByName(name)
+
+	if !isMethod && (!isField || !field.IsExported()) {
+		c.err = fmt.Errorf("unknown field .%s", name)
+		return
+	}
+
+	if c.dataType != commandContextType || !isMethod || len(ident) > 1 || len(args) == 0 {
+		return
+	}
+
+	for _, method := range argumentMethods {
+		if method != name {
+			continue
+		}
+
+		number, ok := args[0].(*parse.NumberNode)
+		if !ok || !number.IsInt {
+			return
+		}
+
+		if number.Int64 < 1 {
+			c.err = fmt.Errorf("invalid argument %s", strconv.FormatInt(number.Int64, 10))
+		} else if !c.variadic && int(number.Int64) > c.arguments {
+			c.err = fmt.Errorf("argument %d exceeds %d arguments", number.Int64, c.arguments)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package schema_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ubavic/mint/schema"
+)
+
+func TestTemplate(t *testing.T) {
+	testCases := []struct {
+		template       string
+		arguments      int
+		variadic       bool
+		expectedResult string
+		expectedError  error
+	}{
+		{
+			template:       "<b>{{.Arg 1}}</b>",
+			arguments:      1,
+			expectedResult: "<b>[1]</b>",
+		},
+		{
+			template:       "<figure>{{.Arg 1}}{{if .Arg 2}}<figcaption>{{.Arg 2}}</figcaption>{{end}}</figure>",
+			arguments:      2,
+			expectedResult: "<figure>[1]<figcaption>[2]</figcaption></figure>",
+		},
+		{
+			template:       "<ul>{{range .Args}}<li>{{.}}</li>{{end}}</ul>",
+			arguments:      1,
+			variadic:       true,
+			expectedResult: "<ul><li>[1]</li><li>[2]</li><li>[3]</li></ul>",
+		},
+		{
+			template:       `<h2 id="{{slug (.Text 1)}}">{{upper (.Text 1)}}</h2>`,
+			arguments:      1,
+			expectedResult: `<h2 id="hello-world">  HELLO, WORLD! </h2>`,
+		},
+		{
+			template:       "{{.Name}} in {{.Parent}} by {{.Meta.author}}{{.Meta.missing}}",
+			expectedResult: "cmd in section by Ana",
+		},
+		{
+			template:       "{{range .Args}}{{.}}{{$.Name}}{{end}}",
+			arguments:      1,
+			expectedResult: "[1]cmd",
+		},
+		{
+			template:       "{{(.Text 1) | trim | lower}}",
+			arguments:      1,
+			expectedResult: "hello, world!",
+		},
+		{
+			template:      "{{range .Args}}{{$.Arg 3}}{{end}}",
+			arguments:     2,
+			expectedError: schema.ErrInvalidTemplate,
+		},
+		{
+			template:      "{{(.Arg 3) | upper}}",
+			arguments:     2,
+			expectedError: schema.ErrInvalidTemplate,
+		},
+		{
+			template:      "{{.Arg 3}}",
+			arguments:     2,
+			expectedError: schema.ErrInvalidTemplate,
+		},
+		{
+			template:      "{{.Text 0}}",
+			arguments:     2,
+			expectedError: schema.ErrInvalidTemplate,
+		},
+		{
+			template:      "{{.Params.id}}",
+			expectedError: schema.ErrInvalidTemplate,
+		},
+		{
+			template:      "{{.render 1}}",
+			expectedError: schema.ErrInvalidTemplate,
+		},
+		{
+			template:      "{{if .Arg 1}}",
+			arguments:     1,
+			expectedError: schema.ErrInvalidTemplate,
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(
+			fmt.Sprintf("TestTemplate%d", i),
+			func(t *testing.T) {
+				tmpl, err := schema.ParseTemplate("cmd", testCase.template, testCase.arguments, testCase.variadic)
+				if !errors.Is(err, testCase.expectedError) {
+					t.Fatalf("Expected error \"%v\", got \"%v\"", testCase.expectedError, err)
+				}
+
+				if err != nil {
+					return
+				}
+
+				arguments := max(testCase.arguments, 3)
+				if !testCase.variadic {
+					arguments = testCase.arguments
+				}
+
+				context := schema.NewCommandContext(
+					"cmd",
+					"section",
+					map[string]string{"author": "Ana"},
+					arguments,
+					func(i int) (string, error) { return fmt.Sprintf("[%d]", i), nil },
+					func(i int) string { return "  Hello, World! " },
+				)
+
+				var result strings.Builder
+
+				err = tmpl.Execute(&result, context)
+				if err != nil {
+					t.Fatalf("Expected no error, got \"%v\"", err)
+				}
+
+				if result.String() != testCase.expectedResult {
+					t.Errorf("Expected \"%s\", got \"%s\"", testCase.expectedResult, result.String())
+				}
+			},
+		)
+	}
+}
+
+func TestSlug(t *testing.T) {
+	testCases := map[string]string{
+		"":                     "",
+		"Hello":                "hello",
+		"  Hello, World!  ":    "hello-world",
+		"Čitanje 2. poglavlja": "čitanje-2-poglavlja",
+	}
+
+	for input, expected := range testCases {
+		if result := schema.Slug(input); result != expected {
+			t.Errorf("Slug(\"%s\"): expected \"%s\", got \"%s\"", input, expected, result)
+		}
+	}
+}
+
+func TestDocumentTemplate(t *testing.T) {
+	testCases := []struct {
+		template       string
+		expectedResult string
+		expectedError  error
+	}{
+		{
+			template:       "<title>{{.Meta.title}}</title>{{.Body}}",
+			expectedResult: "<title>T</title>body",
+		},
+		{
+			template:       "{{range .Packages}}\\usepackage{ {{- . -}} }\n{{end}}",
+			expectedResult: "\\usepackage{a}\n\\usepackage{b}\n",
+		},
+		{
+			template:      "{{.Arg 1}}",
+			expectedError: schema.ErrInvalidTemplate,
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(
+			fmt.Sprintf("TestDocumentTemplate%d", i),
+			func(t *testing.T) {
+				tmpl, err := schema.ParseDocumentTemplate("document", testCase.template)
+				if !errors.Is(err, testCase.expectedError) {
+					t.Fatalf("Expected error \"%v\", got \"%v\"", testCase.expectedError, err)
+				}
+
+				if err != nil {
+					return
+				}
+
+				var result strings.Builder
+
+				err = tmpl.Execute(&result, &schema.DocumentContext{
+					Body:     "body",
+					Meta:     map[string]string{"title": "T"},
+					Packages: []string{"a", "b"},
+				})
+				if err != nil {
+					t.Fatalf("Expected no error, got \"%v\"", err)
+				}
+
+				if result.String() != testCase.expectedResult {
+					t.Errorf("Expected \"%s\", got \"%s\"", testCase.expectedResult, result.String())
+				}
+			},
+		)
+	}
+}
@@ -3,76 +3,192 @@ package schema
 import (
 	"errors"
 	"fmt"
-	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/ubavic/mint/parser"
 )
 
 var ErrCommandNotAllowed = errors.New("command not allowed")
+var ErrInvalidContent = errors.New("invalid content")
+var ErrTextNotAllowed = errors.New("text not allowed")
 var ErrCommandNotFound = errors.New("command not found")
 var ErrCommandInvalidArguments = errors.New("command has invalid arguments")
 var ErrGroupNotFound = errors.New("group not found")
 var ErrTargetNotFound = errors.New("target not found")
+var ErrCounterNotFound = errors.New("counter not found")
 
+// Validate checks that commands appear only where groups of the schema allow
+// them: at the root, or in arguments of commands with AllowChildren. Limits of
+// occurrences, the order of ordered groups, and text where it is not allowed
+// are reported as well.
 func (s Schema) Validate(document parser.Element) error {
-	return s.validate(document, nil)
+	errs := []error{}
+	s.validate(document.Content(), s.rootContent(), "the root", parser.Position{Line: 1, Column: 1}, &errs)
+
+	return errors.Join(errs...)
 }
 
-func (s Schema) validate(document parser.Element, parent *string) error {
-	var parentAllowedCommands []string
-	var err error
+// AllowsText reports whether non-whitespace text is allowed where the text
+// setting and the group apply, like the Text and AllowChildren of a command.
+// An unset setting falls back to the Text of the group, and text is allowed
+// when both are unset.
+func (s *Schema) AllowsText(text *bool, groupName string) bool {
+	if text != nil {
+		return *text
+	}
+
+	group, err := s.GetGroup(groupName)
+	if err == nil && group.Text != nil {
+		return *group.Text
+	}
+
+	return true
+}
+
+// validate checks elements against the content model, and arguments of each
+// command against their own models
+func (s *Schema) validate(elements []parser.Element, c *content, where string, position parser.Position, errs *[]error) {
+	for _, el := range elements {
+		switch el := el.(type) {
+		case *parser.Command:
+			children, ok := s.commandContent(el.Name)
+			if !ok {
+				continue
+			}
+
+			for _, argument := range el.Arguments {
+				s.validate(argument.Content(), children, "command "+el.Name, el.Position, errs)
+			}
+		case *parser.TextContent:
+			if c.text {
+				continue
+			}
 
-	if parent == nil {
-		if s.Source.AllowedRootCommands != "" {
-			parentAllowedCommands, err = s.GetGroupCommands(s.Source.AllowedRootCommands)
-			if err != nil {
-				return fmt.Errorf("%w: parent allowed commands", err)
+			text := strings.TrimSpace(el.TextContent)
+			if text != "" {
+				*errs = append(*errs, &parser.Error{Position: textPosition(el), Err: fmt.Errorf("%w: \"%s\" in %s", ErrTextNotAllowed, excerpt(text), where)})
 			}
 		}
-	} else {
-		command, err := s.GetCommand(*parent)
-		if err != nil {
-			return fmt.Errorf("%w: command %s", err, command.Command)
+	}
+
+	if c.err != nil {
+		*errs = append(*errs, &parser.Error{Position: position, Err: fmt.Errorf("%w of %s", c.err, where)})
+		return
+	}
+
+	if c.group == nil {
+		return
+	}
+
+	counts := map[string]int{}
+	last := -1
+
+	for _, el := range elements {
+		command, ok := el.(*parser.Command)
+		if !ok {
+			continue
 		}
 
-		parentAllowedCommands, err = s.GetGroupCommands(command.Command)
-		if err != nil {
-			return fmt.Errorf("%w: command %s", err, command.Command)
+		index, ok := c.positions[command.Name]
+		if !ok {
+			*errs = append(*errs, &parser.Error{Position: command.Position, Err: &notAllowedError{message: fmt.Sprintf("command %s in %s, expected one of %v", command.Name, where, c.group.Commands)}})
+			continue
 		}
-	}
 
-	if parentAllowedCommands == nil {
-		return nil
+		counts[command.Name]++
+
+		limit := c.limits[command.Name]
+		if limit.Max > 0 && counts[command.Name] == limit.Max+1 {
+			*errs = append(*errs, &parser.Error{Position: command.Position, Err: fmt.Errorf("%w: command %s appears more than %d times in %s", ErrInvalidContent, command.Name, limit.Max, where)})
+		}
+
+		if c.group.Ordered && index < last {
+			*errs = append(*errs, &parser.Error{Position: command.Position, Err: fmt.Errorf("%w: command %s must precede %s in %s", ErrInvalidContent, command.Name, c.group.Commands[last], where)})
+		}
+		last = max(last, index)
 	}
 
-	for _, el := range document.Content() {
-		if command, ok := el.(*parser.Command); ok {
-			if !slices.Contains(parentAllowedCommands, command.Name) {
-				return fmt.Errorf("%w: command %s is not in list %v", ErrCommandNotFound, command.Name, parentAllowedCommands)
-			}
+	for _, occurrence := range c.group.Occurrences {
+		if counts[occurrence.Command] < occurrence.Min {
+			*errs = append(*errs, &parser.Error{Position: position, Err: fmt.Errorf("%w: %s requires at least %d of command %s, found %d", ErrInvalidContent, where, occurrence.Min, occurrence.Command, counts[occurrence.Command])})
 		}
 	}
+}
+
+// notAllowedError is ErrCommandNotAllowed, and ErrCommandNotFound as well,
+// which was reported for commands outside the allowed group before
+type notAllowedError struct {
+	message string
+}
+
+func (e *notAllowedError) Error() string {
+	return ErrCommandNotAllowed.Error() + ": " + e.message
+}
+
+func (e *notAllowedError) Is(target error) bool {
+	return target == ErrCommandNotAllowed || target == ErrCommandNotFound
+}
+
+// excerpt shortens text for messages
+func excerpt(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+
+	runes := []rune(text)
+	if len(runes) > 20 {
+		return string(runes[:20]) + "…"
+	}
 
-	return nil
+	return text
+}
+
+// textPosition returns the position of the first non-whitespace character
+func textPosition(text *parser.TextContent) parser.Position {
+	position := text.Position
+	leading := text.TextContent[:len(text.TextContent)-len(strings.TrimLeftFunc(text.TextContent, unicode.IsSpace))]
+
+	if newline := strings.LastIndexByte(leading, '\n'); newline >= 0 {
+		position.Line += strings.Count(leading, "\n")
+		position.Column = 1
+		leading = leading[newline+1:]
+	}
+
+	position.Column += utf8.RuneCountInString(leading)
+
+	return position
 }
 
 func (s Schema) ValidateSingleCommand(name string, args int) error {
-	for _, command := range s.Source.Commands {
-		if command.Command == name {
-			if command.Arguments == args {
-				return nil
-			} else {
-				return fmt.Errorf("%w: command %s requires %d arguments, but %d is given", ErrCommandInvalidArguments, name, command.Arguments, args)
-			}
-		}
+	command, err := s.GetCommand(name)
+	if err != nil {
+		return fmt.Errorf("%w: command %s is not found in the schema", ErrCommandNotFound, name)
 	}
 
-	return fmt.Errorf("%w: command %s is not found in the schema", ErrCommandNotFound, name)
+	if command.Arguments == args || (command.Variadic && args > command.Arguments) {
+		return nil
+	} else if command.Variadic {
+		return fmt.Errorf("%w: command %s requires at least %d arguments, but %d is given", ErrCommandInvalidArguments, name, command.Arguments, args)
+	} else {
+		return fmt.Errorf("%w: command %s requires %d arguments, but %d is given", ErrCommandInvalidArguments, name, command.Arguments, args)
+	}
 }
 
+// GetCommand returns the command definition. The definition of a loaded
+// schema is shared, and must not be changed.
 func (s *Schema) GetCommand(commandName string) (*Command, error) {
-	for _, command := range s.Source.Commands {
-		if command.Command == commandName {
+	if s.index != nil {
+		command, ok := s.index.commands[commandName]
+		if !ok {
+			return nil, ErrCommandNotFound
+		}
+
+		return command, nil
+	}
+
+	for i := range s.Source.Commands {
+		if s.Source.Commands[i].Command == commandName {
+			command := s.Source.Commands[i]
 			return &command, nil
 		}
 	}
@@ -80,16 +196,52 @@ func (s *Schema) GetCommand(commandName string) (*Command, error) {
 	return nil, ErrCommandNotFound
 }
 
+// GetGroupCommands returns commands of the group, including commands of
+// included groups, without excluded ones
 func (s *Schema) GetGroupCommands(groupName string) ([]string, error) {
-	for _, group := range s.Source.Groups {
-		if group.Name == groupName {
-			return group.Commands, nil
+	return s.groupCommands(groupName)
+}
+
+func (s *Schema) GetGroup(groupName string) (*Group, error) {
+	if s.index != nil {
+		group, ok := s.index.groups[groupName]
+		if !ok {
+			return nil, ErrGroupNotFound
+		}
+
+		return group, nil
+	}
+
+	for i := range s.Source.Groups {
+		if s.Source.Groups[i].Name == groupName {
+			group := s.Source.Groups[i]
+			return &group, nil
 		}
 	}
 
 	return nil, ErrGroupNotFound
 }
 
+func (s *Schema) GetCounter(counterName string) (*Counter, error) {
+	if s.index != nil {
+		counter, ok := s.index.counters[counterName]
+		if !ok {
+			return nil, ErrCounterNotFound
+		}
+
+		return counter, nil
+	}
+
+	for i := range s.Source.Counters {
+		if s.Source.Counters[i].Name == counterName {
+			counter := s.Source.Counters[i]
+			return &counter, nil
+		}
+	}
+
+	return nil, ErrCounterNotFound
+}
+
 func (s *Schema) GetTarget(targetName string) (*Target, error) {
 	if targetName == "" {
 		if len(s.Targets) == 0 {
@@ -99,8 +251,18 @@ func (s *Schema) GetTarget(targetName string) (*Target, error) {
 		}
 	}
 
-	for _, target := range s.Targets {
-		if target.Name == targetName {
+	if s.index != nil {
+		target, ok := s.index.targets[targetName]
+		if !ok {
+			return nil, ErrTargetNotFound
+		}
+
+		return target, nil
+	}
+
+	for i := range s.Targets {
+		if s.Targets[i].Name == targetName {
+			target := s.Targets[i]
 			return &target, nil
 		}
 	}
@@ -1,12 +1,15 @@
 package schema_test
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/ubavic/mint/parser"
 	"github.com/ubavic/mint/schema"
+	"gopkg.in/yaml.v3"
 )
 
 func TestSchemaValidator(t *testing.T) {
@@ -130,3 +133,273 @@ func TestSchemaValidator(t *testing.T) {
 	}
 
 }
+
+const contentModelSchema = `
+source:
+  allowedRootChildren: document
+  commands:
+    - command: title
+      arguments: 1
+    - command: section
+      arguments: 1
+      allowChildren: sectionContent
+    - command: p
+      arguments: 1
+    - command: figure
+      arguments: 1
+      allowChildren: figureContent
+    - command: image
+      arguments: 1
+    - command: caption
+      arguments: 1
+  groups:
+    - name: document
+      commands: [title, section]
+      ordered: true
+      occurrences:
+        - command: title
+          min: 1
+          max: 1
+        - command: section
+          min: 1
+    - name: sectionContent
+      commands: [p, figure]
+    - name: figureContent
+      commands: [image, caption]
+      occurrences:
+        - command: caption
+          min: 1
+          max: 1
+`
+
+func TestSchemaValidatorContentModel(t *testing.T) {
+	s, err := schema.Load([]byte(contentModelSchema))
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	testCases := []struct {
+		input         string
+		expectedError error
+		expectedText  string
+	}{
+		{
+			input: "@title{T}\n@section{@p{a}@figure{@image{x}@caption{c}}}\n@section{@p{b}}",
+		},
+		{
+			input:         "@section{@p{a}}",
+			expectedError: schema.ErrInvalidContent,
+			expectedText:  "1:1: invalid content: the root requires at least 1 of command title, found 0",
+		},
+		{
+			input:         "@title{T}\n@title{U}\n@section{}",
+			expectedError: schema.ErrInvalidContent,
+			expectedText:  "2:1: invalid content: command title appears more than 1 times in the root",
+		},
+		{
+			input:         "@section{}\n@title{T}",
+			expectedError: schema.ErrInvalidContent,
+			expectedText:  "2:1: invalid content: command title must precede section in the root",
+		},
+		{
+			input:         "@title{T}\n@section{@figure{@image{x}}}",
+			expectedError: schema.ErrInvalidContent,
+			expectedText:  "2:10: invalid content: command figure requires at least 1 of command caption, found 0",
+		},
+		{
+			input:         "@title{T}\n@section{@caption{c}}",
+			expectedError: schema.ErrCommandNotAllowed,
+			expectedText:  "2:10: command not allowed: command caption in command section, expected one of [p figure]",
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(fmt.Sprintf("TestSchemaValidatorContentModel%d", i), func(t *testing.T) {
+			tokenizer := parser.NewTokenizer(bufio.NewReader(strings.NewReader(testCase.input)))
+			p := parser.NewParser(tokenizer.Tokenize(), s)
+
+			_, err := p.Parse()
+			if !errors.Is(err, testCase.expectedError) {
+				t.Fatalf("Expected error \"%v\", got \"%v\"", testCase.expectedError, err)
+			}
+
+			if testCase.expectedText != "" && !strings.Contains(err.Error(), testCase.expectedText) {
+				t.Errorf("Expected error \"%s\", got \"%v\"", testCase.expectedText, err)
+			}
+		})
+	}
+}
+
+func TestSchemaValidatorText(t *testing.T) {
+	s, err := schema.Load([]byte(`
+source:
+  allowedRootChildren: blocks
+  commands:
+    - command: section
+      arguments: 1
+    - command: list
+      arguments: 1
+      allowChildren: items
+    - command: item
+      arguments: 1
+    - command: p
+      arguments: 1
+      allowChildren: items
+      text: true
+  groups:
+    - name: blocks
+      commands: [section, list, p]
+      text: false
+    - name: items
+      commands: [item]
+      text: false
+`))
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	testCases := []struct {
+		input         string
+		expectedError error
+		expectedText  string
+	}{
+		{
+			input: "@section{Text}\n\n@list{\n  @item{a}\n  @item{b}\n}\n@p{text @item{c}}\n",
+		},
+		{
+			input:         "@section{A}\nstray text between sections, which is long\n@section{B}",
+			expectedError: schema.ErrTextNotAllowed,
+			expectedText:  "2:1: text not allowed: \"stray text between s…\" in the root",
+		},
+		{
+			input:         "@list{\n  @item{a}\n  oops\n}",
+			expectedError: schema.ErrTextNotAllowed,
+			expectedText:  "3:3: text not allowed: \"oops\" in command list",
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(fmt.Sprintf("TestSchemaValidatorText%d", i), func(t *testing.T) {
+			tokenizer := parser.NewTokenizer(bufio.NewReader(strings.NewReader(testCase.input)))
+			p := parser.NewParser(tokenizer.Tokenize(), s)
+
+			_, err := p.Parse()
+			if !errors.Is(err, testCase.expectedError) {
+				t.Fatalf("Expected error \"%v\", got \"%v\"", testCase.expectedError, err)
+			}
+
+			if testCase.expectedText != "" && !strings.Contains(err.Error(), testCase.expectedText) {
+				t.Errorf("Expected error \"%s\", got \"%v\"", testCase.expectedText, err)
+			}
+		})
+	}
+}
+
+// largeSchema has 400 commands: blocks allowed at the root, each containing
+// inline commands
+func largeSchema() *schema.Schema {
+	s := &schema.Schema{}
+	s.Source.AllowedRootCommands = "blocks"
+
+	blocks := schema.Group{Name: "blocks"}
+	inline := schema.Group{Name: "inline"}
+
+	for i := range 400 {
+		command := schema.Command{Command: fmt.Sprintf("c%d", i), Arguments: 1}
+
+		if i < 200 {
+			command.AllowChildren = "inline"
+			blocks.Commands = append(blocks.Commands, command.Command)
+		} else {
+			inline.Commands = append(inline.Commands, command.Command)
+		}
+
+		s.Source.Commands = append(s.Source.Commands, command)
+	}
+
+	s.Source.Groups = []schema.Group{blocks, inline}
+
+	return s
+}
+
+// benchmarkSchemas returns the large schema built in code, which is searched
+// linearly, and compiled and loaded, which are indexed
+func benchmarkSchemas(b *testing.B) []struct {
+	name   string
+	schema *schema.Schema
+} {
+	compiled := largeSchema()
+
+	err := compiled.Compile()
+	if err != nil {
+		b.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	encoded, err := yaml.Marshal(largeSchema())
+	if err != nil {
+		b.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	loaded, err := schema.Load(encoded)
+	if err != nil {
+		b.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	return []struct {
+		name   string
+		schema *schema.Schema
+	}{
+		{name: "Built", schema: largeSchema()},
+		{name: "Compiled", schema: compiled},
+		{name: "Loaded", schema: loaded},
+	}
+}
+
+func largeDocument(blocks int) string {
+	var document strings.Builder
+
+	for i := range blocks {
+		fmt.Fprintf(&document, "@c%d{text @c%d{a} and @c%d{b}}\n", i%200, 200+i%200, 399-i%200)
+	}
+
+	return document.String()
+}
+
+func BenchmarkValidate(b *testing.B) {
+	tokenizer := parser.NewTokenizer(bufio.NewReader(strings.NewReader(largeDocument(25000))))
+	p := parser.NewParser(tokenizer.Tokenize(), &parser.OptimisticValidator{})
+
+	document, err := p.Parse()
+	if err != nil {
+		b.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	for _, benchmark := range benchmarkSchemas(b) {
+		b.Run(benchmark.name, func(b *testing.B) {
+			for range b.N {
+				err := benchmark.schema.Validate(document)
+				if err != nil {
+					b.Fatalf("Expected no error, got \"%v\"", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	tokenizer := parser.NewTokenizer(bufio.NewReader(strings.NewReader(largeDocument(25000))))
+	tokens := tokenizer.Tokenize()
+
+	for _, benchmark := range benchmarkSchemas(b) {
+		b.Run(benchmark.name, func(b *testing.B) {
+			for range b.N {
+				p := parser.NewParser(tokens, benchmark.schema)
+
+				_, err := p.Parse()
+				if err != nil {
+					b.Fatalf("Expected no error, got \"%v\"", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package schema
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FormatVersion is the latest version of the schema format, declared in
+// schemas as `mint: v0.2`. Schemas without the declaration are read in the
+// latest format.
+const FormatVersion = "v0.2"
+
+var ErrInvalidVersion = errors.New("invalid schema version")
+var ErrUnsupportedVersion = errors.New("unsupported schema version")
+
+// migration upgrades a schema document to the version
+type migration struct {
+	version     string
+	description string
+	apply       func(document *yaml.Node)
+}
+
+var migrations = []migration{
+	{
+		version:     "v0.2",
+		description: "escape literal $ in expressions as $$",
+		apply:       escapeExpressionDollars,
+	},
+}
+
+// Migrate upgrades a schema to the latest format, keeping comments and the
+// order of keys. It returns the upgraded schema and descriptions of applied
+// migrations, which are empty when the schema is already in the latest
+// format.
+func Migrate(data []byte) ([]byte, []string, error) {
+	var root yaml.Node
+	err := yaml.Unmarshal(data, &root)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't unmarshal schema: %w", err)
+	}
+
+	applied, err := migrate(&root)
+	if err != nil || len(applied) == 0 {
+		return data, applied, err
+	}
+
+	migrated, err := encodeNode(&root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return migrated, applied, nil
+}
+
+// migrate applies migrations newer than the declared version to the document
+// node, and updates the declared version
+func migrate(root *yaml.Node) ([]string, error) {
+	if len(root.Content) == 0 {
+		return []string{}, nil
+	}
+
+	declared := mappingValue(root.Content[0], "mint")
+	if declared == nil || declared.Value == "" {
+		return []string{}, nil
+	}
+
+	version, err := checkVersion(declared.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := []string{}
+
+	for _, m := range migrations {
+		migrationVersion, _ := parseVersion(m.version)
+		if compareVersions(version, migrationVersion) >= 0 {
+			continue
+		}
+
+		m.apply(root.Content[0])
+		applied = append(applied, m.version+": "+m.description)
+	}
+
+	if len(applied) > 0 {
+		declared.Value = FormatVersion
+	}
+
+	return applied, nil
+}
+
+// checkVersion parses the declared version, and rejects versions newer than
+// FormatVersion
+func checkVersion(declared string) ([2]int, error) {
+	version, err := parseVersion(declared)
+	if err != nil {
+		return version, err
+	}
+
+	latest, _ := parseVersion(FormatVersion)
+	if compareVersions(version, latest) > 0 {
+		return version, fmt.Errorf("%w: %s is newer than %s, the latest version supported by this mint", ErrUnsupportedVersion, declared, FormatVersion)
+	}
+
+	return version, nil
+}
+
+// parseVersion parses versions like v0.2 into major and minor numbers
+func parseVersion(version string) ([2]int, error) {
+	major, minor, ok := strings.Cut(strings.TrimPrefix(version, "v"), ".")
+
+	majorNumber, majorErr := strconv.Atoi(major)
+	minorNumber, minorErr := strconv.Atoi(minor)
+
+	if !ok || majorErr != nil || minorErr != nil || majorNumber < 0 || minorNumber < 0 {
+		return [2]int{}, fmt.Errorf("%w: \"%s\", expected a version like %s", ErrInvalidVersion, version, FormatVersion)
+	}
+
+	return [2]int{majorNumber, minorNumber}, nil
+}
+
+func compareVersions(a, b [2]int) int {
+	if a[0] != b[0] {
+		return a[0] - b[0]
+	}
+
+	return a[1] - b[1]
+}
+
+func encodeNode(root *yaml.Node) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+
+	err := encoder.Encode(root)
+	if err != nil {
+		return nil, fmt.Errorf("can't marshal schema: %w", err)
+	}
+
+	err = encoder.Close()
+	if err != nil {
+		return nil, fmt.Errorf("can't marshal schema: %w", err)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// escapeExpressionDollars doubles each $ that isn't followed by an argument
+// number. In v0.1, only `$1` to `$9` were replaced, and other dollars were
+// written as they are.
+func escapeExpressionDollars(document *yaml.Node) {
+	for _, target := range sequence(mappingValue(document, "targets"), -1) {
+		for _, command := range sequence(mappingValue(target, "commands"), -1) {
+			expression := mappingValue(command, "expression")
+			if expression == nil || expression.Kind != yaml.ScalarNode {
+				continue
+			}
+
+			var escaped strings.Builder
+			for i := 0; i < len(expression.Value); i++ {
+				escaped.WriteByte(expression.Value[i])
+
+				if expression.Value[i] == '$' && (i+1 >= len(expression.Value) || expression.Value[i+1] < '1' || expression.Value[i+1] > '9') {
+					escaped.WriteByte('$')
+				}
+			}
+
+			expression.Value = escaped.String()
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package schema_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ubavic/mint/schema"
+)
+
+const oldSchema = `mint: v0.1
+# inline math
+source:
+  commands:
+    - command: math
+      arguments: 1
+targets:
+  - name: Latex
+    commands:
+      - command: math
+        expression: "$$1$ costs 5$"
+`
+
+func TestMigrate(t *testing.T) {
+	migrated, applied, err := schema.Migrate([]byte(oldSchema))
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	if len(applied) != 1 {
+		t.Errorf("Expected one migration, got %v", applied)
+	}
+
+	for _, expected := range []string{"mint: v0.2", "# inline math", `expression: "$$$1$$ costs 5$$"`} {
+		if !strings.Contains(string(migrated), expected) {
+			t.Errorf("Expected migrated schema to contain %s, got:\n%s", expected, migrated)
+		}
+	}
+
+	again, applied, err := schema.Migrate(migrated)
+	if err != nil || len(applied) != 0 || string(again) != string(migrated) {
+		t.Errorf("Expected migrated schema to be unchanged, got %v and:\n%s", applied, again)
+	}
+}
+
+func TestLoadMigratesOldVersions(t *testing.T) {
+	s, err := schema.Load([]byte(oldSchema))
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	target, _ := s.GetTarget("Latex")
+	if target.Commands[0].Expression != "$$$1$$ costs 5$$" {
+		t.Errorf("Expected migrated expression, got %s", target.Commands[0].Expression)
+	}
+}
+
+func TestLoadVersionErrors(t *testing.T) {
+	testCases := []struct {
+		version       string
+		expectedError error
+	}{
+		{version: "v0.2"},
+		{version: `""`},
+		{version: "v0.3", expectedError: schema.ErrUnsupportedVersion},
+		{version: "v1.0", expectedError: schema.ErrUnsupportedVersion},
+		{version: "latest", expectedError: schema.ErrInvalidVersion},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(fmt.Sprintf("TestLoadVersionErrors%d", i), func(t *testing.T) {
+			_, err := schema.Load([]byte("mint: " + testCase.version + "\n"))
+			if !errors.Is(err, testCase.expectedError) {
+				t.Fatalf("Expected error \"%v\", got \"%v\"", testCase.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestLintKeepsLinesOfOldVersions(t *testing.T) {
+	files := fstest.MapFS{
+		"schema.yaml": {Data: []byte(`mint: v0.1
+
+# commands
+
+source:
+  commands:
+    - command: price
+
+      argumnts: 1
+    - command: price
+      arguments: 1
+targets:
+  - name: HTML
+    commands:
+      - command: price
+        expression: "$$1"
+`)},
+	}
+
+	issues, err := schema.LintFS(files, "schema.yaml")
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	expected := "[schema.yaml:9: unknown key argumnts schema.yaml:10: duplicate command price, first defined at line 7 schema.yaml:15: target HTML, command price: invalid expression: placeholder $1 exceeds 0 arguments]"
+	if fmt.Sprint(issues) != expected {
+		t.Errorf("Expected issues %s, got %v", expected, issues)
+	}
+}
+
+func TestMigrateWithoutVersion(t *testing.T) {
+	for i, data := range []string{"source: {}\n", "mint: \"\"\nsource: {}\n"} {
+		t.Run(fmt.Sprintf("TestMigrateWithoutVersion%d", i), func(t *testing.T) {
+			migrated, applied, err := schema.Migrate([]byte(data))
+			if err != nil || len(applied) != 0 || string(migrated) != data {
+				t.Errorf("Expected schema to be unchanged, got %v, %v and:\n%s", err, applied, migrated)
+			}
+		})
+	}
+}
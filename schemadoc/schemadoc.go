@@ -0,0 +1,495 @@
+// Package schemadoc generates a reference page of the commands of a schema.
+//
+// The page is an atex document valid against the schema returned by Schema,
+// and it is rendered like any other document, for the Markdown or the HTML
+// target. For each command, the page lists its arguments and settings like
+// counters and headings, where the command is allowed, what it may contain,
+// and how it is rendered by each target of the documented schema.
+package schemadoc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ubavic/mint"
+	"github.com/ubavic/mint/numbering"
+	"github.com/ubavic/mint/parser"
+	"github.com/ubavic/mint/schema"
+	"github.com/ubavic/mint/writer"
+)
+
+const pageSchema = `mint: v0.2
+name: Schema reference
+source:
+  allowedRootChildren: page
+  rootText: false
+  commands:
+    - command: title
+      description: Title of the page
+      arguments: 1
+    - command: section
+      description: Section heading
+      arguments: 1
+    - command: p
+      description: Paragraph
+      arguments: 1
+      allowChildren: inline
+    - command: command
+      description: Reference of a command, with its name and entries
+      arguments: 2
+      allowChildren: entries
+    - command: fields
+      description: List of fields
+      arguments: 1
+      allowChildren: fieldList
+    - command: field
+      description: Field with a name and a value
+      arguments: 2
+      allowChildren: inline
+    - command: example
+      description: Rendering of a command by the named target
+      arguments: 2
+      allowChildren: none
+    - command: code
+      description: Source code
+      arguments: 1
+      allowChildren: none
+    - command: ref
+      description: Link to the reference of the named command
+      arguments: 1
+      allowChildren: none
+  groups:
+    - name: page
+      commands: [title, section, p, command]
+    - name: entries
+      commands: [p, fields, example]
+    - name: fieldList
+      commands: [field]
+      text: false
+    - name: inline
+      commands: [code, ref]
+    - name: none
+targets:
+  - name: Markdown
+    extension: md
+    escape: none
+    commands:
+      - command: title
+        expression: "# $1\n\n"
+      - command: section
+        expression: "## $1\n\n"
+      - command: p
+        expression: "$1\n\n"
+      - command: command
+        expression: "### <a id=\"command-$1\"></a>` + "`$1`" + `\n\n$2"
+      - command: fields
+        expression: "$1\n"
+      - command: field
+        expression: "- $1: $2\n"
+      - command: example
+        expression: "$1:\n\n` + "```" + `\n$2\n` + "```" + `\n\n"
+      - command: code
+        expression: "` + "`$1`" + `"
+      - command: ref
+        expression: "[` + "`$1`" + `](#command-$1)"
+  - name: HTML
+    extension: html
+    escape: html
+    document: |
+      <!DOCTYPE html>
+      <html>
+      <head>
+      <meta charset="utf-8">
+      <title>{{escape "html" .Meta.title}}</title>
+      </head>
+      <body>
+      {{.Body}}
+      </body>
+      </html>
+    commands:
+      - command: title
+        expression: "<h1>$1</h1>\n"
+      - command: section
+        expression: "<h2>$1</h2>\n"
+      - command: p
+        expression: "<p>$1</p>\n"
+      - command: command
+        expression: "<section id=\"command-$1\">\n<h3><code>$1</code></h3>\n$2</section>\n"
+      - command: fields
+        expression: "<dl>\n$1</dl>\n"
+      - command: field
+        expression: "<dt>$1</dt><dd>$2</dd>\n"
+      - command: example
+        expression: "<p>$1:</p>\n<pre><code>$2</code></pre>\n"
+      - command: code
+        expression: "<code>$1</code>"
+      - command: ref
+        expression: "<a href=\"#command-$1\"><code>$1</code></a>"
+`
+
+// Schema returns the schema of reference pages, with Markdown and HTML
+// targets
+func Schema() (*schema.Schema, error) {
+	return schema.Load([]byte(pageSchema))
+}
+
+// Write renders the reference page of the schema for the named target of
+// Schema
+func Write(ctx context.Context, dst io.Writer, s *schema.Schema, target string) error {
+	page, err := Document(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	pages, err := Schema()
+	if err != nil {
+		return err
+	}
+
+	return mint.Compile(ctx, dst, strings.NewReader(parser.Format(page)), pages,
+		mint.WithTarget(target),
+		mint.WithMetadata(map[string]string{"title": title(s)}),
+	)
+}
+
+// Document returns the reference page of the schema. Examples are rendered
+// for each target of the schema, with placeholders as arguments. Blocks of the
+// page are separated by whitespace only to keep its source readable, and
+// targets of Schema end them with line breaks.
+func Document(ctx context.Context, s *schema.Schema) (*parser.Block, error) {
+	writers := make([]*writer.Writer, len(s.Targets))
+	for i := range s.Targets {
+		w, err := writer.New(&s.Targets[i])
+		if err != nil {
+			return nil, err
+		}
+
+		writers[i] = w
+	}
+
+	page := &parser.Block{}
+	add := func(element parser.Element) {
+		if len(page.Nodes) > 0 {
+			page.Nodes = append(page.Nodes, text("\n\n"))
+		}
+		page.Nodes = append(page.Nodes, element)
+	}
+
+	add(command("title", text(title(s))))
+
+	if about := about(s); about != "" {
+		add(command("p", text(about)))
+	}
+
+	root := []parser.Element{text("The root of a document may contain ")}
+	root = append(root, contains(s, s.Source.AllowedRootCommands)...)
+	root = append(root, text("."))
+	if !s.AllowsText(s.Source.RootText, s.Source.AllowedRootCommands) {
+		root = append(root, text(" Text is not allowed."))
+	}
+	add(command("p", block(root...)))
+
+	add(command("section", text("Commands")))
+
+	for _, c := range s.Source.Commands {
+		entry, err := reference(ctx, s, c, writers)
+		if err != nil {
+			return nil, err
+		}
+
+		add(entry)
+	}
+
+	return page, nil
+}
+
+// reference returns the reference of the command
+func reference(ctx context.Context, s *schema.Schema, c schema.Command, writers []*writer.Writer) (*parser.Command, error) {
+	entries := []parser.Element{}
+	add := func(element parser.Element) {
+		if len(entries) > 0 {
+			entries = append(entries, text("\n\n"))
+		}
+		entries = append(entries, element)
+	}
+
+	if c.Description != "" {
+		add(command("p", text(c.Description)))
+	}
+
+	usage := usage(c)
+
+	fields := []parser.Element{}
+	field := func(name string, value ...parser.Element) {
+		if len(fields) > 0 {
+			fields = append(fields, text("\n"))
+		}
+		fields = append(fields, command("field", text(name), block(value...)))
+	}
+
+	field("Usage", command("code", text(parser.Format(usage))))
+
+	if c.Variadic {
+		field("Arguments", text("at least "+strconv.Itoa(c.Arguments)))
+	} else {
+		field("Arguments", text(strconv.Itoa(c.Arguments)))
+	}
+
+	field("Allowed in", allowedIn(s, c.Command)...)
+	field("May contain", contains(s, c.AllowChildren)...)
+
+	if group, err := s.GetGroup(c.AllowChildren); err == nil {
+		for _, occurrence := range group.Occurrences {
+			field("Occurrences", ref(occurrence.Command), text(": "+occurrences(occurrence)))
+		}
+	}
+
+	if !s.AllowsText(c.Text, c.AllowChildren) {
+		field("Text", text("not allowed"))
+	}
+
+	if c.Counter != "" {
+		field("Counter", command("code", text(c.Counter)))
+	}
+
+	if c.Label > 0 {
+		field("Label", text("argument "+strconv.Itoa(c.Label)))
+	}
+
+	if c.Cite > 0 {
+		field("Cites", text("keys in argument "+strconv.Itoa(c.Cite)))
+	}
+
+	if c.Heading != nil {
+		field("Heading", text(fmt.Sprintf("level %d, with the title in argument %d", c.Heading.Level, c.Heading.Title)))
+	}
+
+	if c.Include {
+		field("Include", text("replaced with the named file"))
+	}
+
+	switch c.Command {
+	case s.Source.TocCommand:
+		field("Replaced with", text("the table of contents"))
+	case s.Source.BibliographyCommand:
+		field("Replaced with", text("the list of cited references"))
+	}
+
+	add(command("fields", block(fields...)))
+
+	for i := range s.Targets {
+		rendered, ok, err := example(ctx, s, &s.Targets[i], writers[i], usage)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			add(command("example", text(s.Targets[i].Name), text(rendered)))
+		}
+	}
+
+	return command("command", text(c.Command), block(entries...)), nil
+}
+
+// example renders the usage of a command by the target. Commands missing in
+// the target are skipped, while errors of rendering without the rest of a
+// document, like unresolved references, are shown in place of the rendering.
+func example(ctx context.Context, s *schema.Schema, target *schema.Target, w *writer.Writer, usage *parser.Command) (string, bool, error) {
+	found := false
+	for _, targetCommand := range target.Commands {
+		found = found || targetCommand.Command == usage.Name
+	}
+
+	if !found {
+		return "", false, nil
+	}
+
+	document := &parser.Block{Nodes: []parser.Element{usage}}
+
+	n, err := numbering.Number(s, document)
+	if err != nil {
+		return "", false, err
+	}
+	w.SetNumbering(n)
+
+	var rendered strings.Builder
+
+	err = w.Write(ctx, &rendered, document)
+	if ctx.Err() != nil {
+		return "", false, ctx.Err()
+	} else if err != nil {
+		// positions of the example are meaningless
+		var positionError *parser.Error
+		if errors.As(err, &positionError) {
+			err = positionError.Err
+		}
+
+		return "not rendered: " + err.Error(), true, nil
+	}
+
+	return rendered.String(), true, nil
+}
+
+// usage returns the command with placeholder arguments
+func usage(c schema.Command) *parser.Command {
+	usage := &parser.Command{Name: c.Command, Arguments: []parser.Element{}}
+
+	for i := range max(c.Arguments, 1) {
+		if i == c.Arguments && !c.Variadic {
+			break
+		}
+
+		usage.Arguments = append(usage.Arguments, block(text("arg"+strconv.Itoa(i+1))))
+	}
+
+	return usage
+}
+
+// allowedIn returns where the command is allowed: at the root, and in
+// arguments of commands
+func allowedIn(s *schema.Schema, name string) []parser.Element {
+	places := []parser.Element{}
+
+	if s.Source.AllowedRootCommands == "" || inGroup(s, s.Source.AllowedRootCommands, name) {
+		places = append(places, text("the root"))
+	}
+
+	for _, c := range s.Source.Commands {
+		if c.AllowChildren == "" || inGroup(s, c.AllowChildren, name) {
+			places = append(places, ref(c.Command))
+		}
+	}
+
+	if len(places) == 0 {
+		return []parser.Element{text("nowhere")}
+	}
+
+	return list(places)
+}
+
+// contains describes commands allowed by the group
+func contains(s *schema.Schema, groupName string) []parser.Element {
+	if groupName == "" {
+		return []parser.Element{text("any command")}
+	}
+
+	commands, err := s.GetGroupCommands(groupName)
+	if err != nil {
+		return []parser.Element{text("commands of the undefined group "), command("code", text(groupName))}
+	}
+
+	if len(commands) == 0 {
+		return []parser.Element{text("no commands")}
+	}
+
+	refs := make([]parser.Element, len(commands))
+	for i, name := range commands {
+		refs[i] = ref(name)
+	}
+
+	elements := list(refs)
+
+	if group, err := s.GetGroup(groupName); err == nil && group.Ordered {
+		elements = append(elements, text(", in this order"))
+	}
+
+	return elements
+}
+
+func inGroup(s *schema.Schema, groupName, name string) bool {
+	commands, err := s.GetGroupCommands(groupName)
+	if err != nil {
+		return false
+	}
+
+	for _, command := range commands {
+		if command == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func occurrences(occurrence schema.Occurrence) string {
+	switch {
+	case occurrence.Max == 0:
+		return fmt.Sprintf("at least %d", occurrence.Min)
+	case occurrence.Min == occurrence.Max:
+		return fmt.Sprintf("exactly %d", occurrence.Min)
+	default:
+		return fmt.Sprintf("from %d to %d", occurrence.Min, occurrence.Max)
+	}
+}
+
+func title(s *schema.Schema) string {
+	if s.Name == "" {
+		return "Schema reference"
+	}
+
+	return s.Name
+}
+
+func about(s *schema.Schema) string {
+	parts := []string{}
+
+	if s.Version != "" {
+		parts = append(parts, "Version "+s.Version)
+	}
+
+	if s.Author != "" {
+		parts = append(parts, "by "+s.Author)
+	}
+
+	if len(parts) == 0 {
+		return ""
+	}
+
+	return strings.Join(parts, ", ") + "."
+}
+
+// list separates elements with commas
+func list(elements []parser.Element) []parser.Element {
+	result := []parser.Element{}
+
+	for i, element := range elements {
+		if i > 0 {
+			result = append(result, text(", "))
+		}
+		result = append(result, element)
+	}
+
+	return result
+}
+
+func ref(name string) *parser.Command {
+	return command("ref", text(name))
+}
+
+// command returns a command whose arguments are wrapped in blocks, as the
+// parser does
+func command(name string, arguments ...parser.Element) *parser.Command {
+	c := &parser.Command{Name: name, Arguments: make([]parser.Element, len(arguments))}
+
+	for i, argument := range arguments {
+		if b, ok := argument.(*parser.Block); ok {
+			c.Arguments[i] = b
+		} else {
+			c.Arguments[i] = block(argument)
+		}
+	}
+
+	return c
+}
+
+func block(nodes ...parser.Element) *parser.Block {
+	return &parser.Block{Nodes: nodes}
+}
+
+func text(content string) *parser.TextContent {
+	return &parser.TextContent{TextContent: content}
+}
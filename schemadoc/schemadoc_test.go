@@ -0,0 +1,145 @@
+package schemadoc_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ubavic/mint/schema"
+	"github.com/ubavic/mint/schemadoc"
+)
+
+const documentedSchema = `
+name: Notes
+source:
+  allowedRootChildren: blocks
+  rootText: false
+  commands:
+    - command: section
+      description: Numbered section
+      arguments: 2
+      counter: section
+      label: 2
+      heading:
+        level: 1
+        title: 1
+    - command: list
+      description: List of items
+      arguments: 1
+      allowChildren: items
+    - command: item
+      arguments: 1
+    - command: row
+      arguments: 1
+      variadic: true
+    - command: ref
+      arguments: 1
+  groups:
+    - name: blocks
+      commands: [section, list, row]
+    - name: items
+      commands: [item]
+      ordered: true
+      text: false
+      occurrences:
+        - command: item
+          min: 1
+  counters:
+    - name: section
+targets:
+  - name: HTML
+    escape: html
+    commands:
+      - command: section
+        template: '<h2 id="{{.Text 2}}">{{.Number}}. {{.Arg 1}}</h2>'
+      - command: list
+        expression: "<ul>$1</ul>"
+      - command: item
+        expression: "<li>$1</li>"
+      - command: ref
+        template: '<a>{{.Ref (.Text 1)}}</a>'
+`
+
+func TestWrite(t *testing.T) {
+	s, err := schema.Load([]byte(documentedSchema))
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	testCases := []struct {
+		target   string
+		expected []string
+	}{
+		{
+			target: "Markdown",
+			expected: []string{
+				"# Notes\n",
+				"The root of a document may contain [`section`](#command-section), [`list`](#command-list), [`row`](#command-row). Text is not allowed.\n",
+				"### <a id=\"command-section\"></a>`section`\n\nNumbered section\n",
+				"- Usage: `@section{arg1}{arg2}`\n",
+				"- Counter: `section`\n- Label: argument 2\n- Heading: level 1, with the title in argument 1\n",
+				"HTML:\n\n```\n<h2 id=\"arg2\">1. arg1</h2>\n```\n",
+				"- May contain: [`item`](#command-item), in this order\n- Occurrences: [`item`](#command-item): at least 1\n- Text: not allowed\n",
+				"- Allowed in: [`section`](#command-section), [`list`](#command-list), [`item`](#command-item), [`row`](#command-row), [`ref`](#command-ref)\n",
+				"- Usage: `@row{arg1}`\n- Arguments: at least 1\n",
+				"- May contain: any command\n\n### <a id=\"command-ref\">",
+				"not rendered: template: ref:1:5: executing \"ref\" at <.Ref>: error calling Ref: undefined reference: arg1\n",
+			},
+		},
+		{
+			target: "HTML",
+			expected: []string{
+				"<title>Notes</title>",
+				"<section id=\"command-list\">\n<h3><code>list</code></h3>\n",
+				"<dt>Usage</dt><dd><code>@list{arg1}</code></dd>\n",
+				"<pre><code>&lt;ul&gt;arg1&lt;/ul&gt;</code></pre>\n",
+			},
+		},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(fmt.Sprintf("TestWrite%d", i), func(t *testing.T) {
+			var result strings.Builder
+
+			err := schemadoc.Write(context.Background(), &result, s, testCase.target)
+			if err != nil {
+				t.Fatalf("Expected no error, got \"%v\"", err)
+			}
+
+			for _, expected := range testCase.expected {
+				if !strings.Contains(result.String(), expected) {
+					t.Errorf("Expected page to contain %q, got:\n%s", expected, result.String())
+				}
+			}
+		})
+	}
+}
+
+func TestWriteExample(t *testing.T) {
+	data, err := os.ReadFile("../example/schema.yaml")
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	s, err := schema.Load(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	for _, target := range []string{"Markdown", "HTML"} {
+		var result strings.Builder
+
+		err = schemadoc.Write(context.Background(), &result, s, target)
+		if err != nil {
+			t.Fatalf("Expected no error, got \"%v\"", err)
+		}
+
+		for _, command := range s.Source.Commands {
+			if !strings.Contains(result.String(), command.Description) {
+				t.Errorf("Expected %s page to describe command %s", target, command.Command)
+			}
+		}
+	}
+}
@@ -1,46 +1,501 @@
 package writer
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"slices"
 	"strings"
+	"unicode/utf8"
 
+	"github.com/ubavic/mint/bibliography"
+	"github.com/ubavic/mint/numbering"
+	"github.com/ubavic/mint/outline"
 	"github.com/ubavic/mint/parser"
 	"github.com/ubavic/mint/schema"
 )
 
-// TODO: use io.Writer for output
-// TODO: cache commandExpression in map
-func Write(targetSchema *schema.Target, element parser.Element) string {
+var ErrCommandNotFound = errors.New("command not found in target")
+var ErrDuplicateCommand = errors.New("duplicate command in target")
+var ErrMissingArgument = errors.New("missing argument")
+
+// Writer renders documents for a single target. It is not safe for
+// concurrent use.
+type Writer struct {
+	target      *schema.Target
+	expressions map[string]*schema.Expression
+	templates   map[string]*schema.Template
+	document    *schema.Template
+	toc         *schema.Template
+	references  *schema.Template
+	packages    map[string][]string
+	collect     map[string]*collector
+	flush       map[string]string
+	metadata    map[string]string
+	text        func(text string, previous rune) string
+	numbering   *numbering.Numbering
+	outline     *outline.Outline
+	citations   *bibliography.Citations
+	maxBytes    int
+
+	// usedPackages are collected during rendering
+	usedPackages []string
+	// previous is the last character of text written so far, so typography
+	// continues across commands
+	previous rune
+	// renderedToc is rendered before the document
+	renderedToc string
+	// entries copy the outline with titles rendered by this writer, since
+	// the outline may be shared by writers of other targets
+	entries []*schema.OutlineEntry
+	// buckets hold collected items that are not flushed yet
+	buckets map[string][]string
+	// indices count items pushed into each bucket
+	indices map[string]int
+}
+
+// collector pushes items rendered by the template into the bucket
+type collector struct {
+	bucket   string
+	template *schema.Template
+}
+
+func New(target *schema.Target) (*Writer, error) {
+	w := &Writer{
+		target:      target,
+		expressions: make(map[string]*schema.Expression, len(target.Commands)),
+		templates:   map[string]*schema.Template{},
+		packages:    map[string][]string{},
+		collect:     map[string]*collector{},
+		flush:       map[string]string{},
+		metadata:    map[string]string{},
+		buckets:     map[string][]string{},
+		indices:     map[string]int{},
+	}
+
+	if target.Document != "" {
+		document, err := schema.ParseDocumentTemplate(target.Name, target.Document)
+		if err != nil {
+			return nil, fmt.Errorf("document in target %s: %w", target.Name, err)
+		}
+		w.document = document
+	}
+
+	if target.Toc != "" {
+		toc, err := schema.ParseTocTemplate(target.Name, target.Toc)
+		if err != nil {
+			return nil, fmt.Errorf("toc in target %s: %w", target.Name, err)
+		}
+		w.toc = toc
+	}
+
+	if target.Bibliography != "" {
+		references, err := schema.ParseBibliographyTemplate(target.Name, target.Bibliography)
+		if err != nil {
+			return nil, fmt.Errorf("bibliography in target %s: %w", target.Name, err)
+		}
+		w.references = references
+	}
+
+	text, err := target.TextTransform()
+	if err != nil {
+		return nil, err
+	}
+	w.text = text
+
+	for _, c := range target.Commands {
+		if w.hasCommand(c.Command) {
+			return nil, fmt.Errorf("%w: command %s in target %s", ErrDuplicateCommand, c.Command, target.Name)
+		}
+
+		w.packages[c.Command] = c.Packages
+
+		if c.Flush != "" {
+			w.flush[c.Command] = c.Flush
+		}
+
+		if c.Collect != nil {
+			tmpl, err := schema.ParseTemplate(c.Command, c.Collect.Template, 0, true)
+			if err != nil {
+				return nil, fmt.Errorf("collect of command %s in target %s: %w", c.Command, target.Name, err)
+			}
+
+			w.collect[c.Command] = &collector{bucket: c.Collect.Bucket, template: tmpl}
+		}
+
+		if c.Template != "" {
+			// arguments are checked when the schema is loaded
+			tmpl, err := schema.ParseTemplate(c.Command, c.Template, 0, true)
+			if err != nil {
+				return nil, fmt.Errorf("command %s in target %s: %w", c.Command, target.Name, err)
+			}
+
+			w.templates[c.Command] = tmpl
+			continue
+		}
+
+		expression, err := schema.ParseExpression(c.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("command %s in target %s: %w", c.Command, target.Name, err)
+		}
+
+		w.expressions[c.Command] = expression
+	}
+
+	return w, nil
+}
+
+func (w *Writer) hasCommand(name string) bool {
+	_, isExpression := w.expressions[name]
+	_, isTemplate := w.templates[name]
+	return isExpression || isTemplate
+}
+
+// SetMetadata sets document metadata available to templates
+func (w *Writer) SetMetadata(metadata map[string]string) {
+	w.metadata = metadata
+}
+
+// SetNumbering sets numbers of commands and labels available to templates
+func (w *Writer) SetNumbering(n *numbering.Numbering) {
+	w.numbering = n
+}
+
+// SetOutline sets headings of the document. The table of contents is written
+// in place of the outline command, if the target has a toc template.
+func (w *Writer) SetOutline(o *outline.Outline) {
+	w.outline = o
+}
+
+// SetCitations sets references cited in the document. The list of references
+// is written in place of the bibliography command, if the target has a
+// bibliography template.
+func (w *Writer) SetCitations(c *bibliography.Citations) {
+	w.citations = c
+}
+
+// SetMaxBytes limits the size of the output. Zero means no limit.
+func (w *Writer) SetMaxBytes(maxBytes int) {
+	w.maxBytes = maxBytes
+}
+
+// Write renders the element to out. It stops when the context is canceled,
+// and returns an error positioned at the first command not found in the
+// target. Output written before the error is not retracted.
+//
+// With an outline, heading titles and the table of contents are rendered
+// first, so the table of contents may precede headings.
+func (w *Writer) Write(ctx context.Context, out io.Writer, element parser.Element) error {
+	w.usedPackages = []string{}
+	w.renderedToc = ""
+	w.entries = nil
+	w.buckets = map[string][]string{}
+	w.indices = map[string]int{}
+	w.previous = 0
+
+	err := w.writeOutline(ctx)
+	if err != nil {
+		return err
+	}
+
+	// items collected from heading titles are collected again in the document
+	w.buckets = map[string][]string{}
+	w.indices = map[string]int{}
+	w.previous = 0
+
+	return w.write(ctx, &limitedWriter{out: out, max: w.maxBytes}, element, "")
+}
+
+func (w *Writer) writeReferences(out *limitedWriter) error {
+	return w.references.Execute(out, &schema.BibliographyContext{References: w.citations.References, Meta: w.metadata})
+}
+
+func (w *Writer) writeOutline(ctx context.Context) error {
+	if w.outline == nil {
+		return nil
+	}
+
+	entries, err := w.renderEntries(ctx, w.outline.Entries)
+	if err != nil {
+		return err
+	}
+
+	w.entries = entries
+	if w.toc == nil {
+		return nil
+	}
+
+	var toc strings.Builder
+
+	err = w.toc.Execute(&limitedWriter{out: &toc, max: w.maxBytes}, &schema.TocContext{Entries: w.entries, Meta: w.metadata})
+	if err != nil {
+		return err
+	}
+
+	w.renderedToc = toc.String()
+
+	return nil
+}
+
+// renderEntries copies entries with rendered titles, parents before children
+func (w *Writer) renderEntries(ctx context.Context, entries []*schema.OutlineEntry) ([]*schema.OutlineEntry, error) {
+	rendered := make([]*schema.OutlineEntry, 0, len(entries))
+
+	for _, entry := range entries {
+		var title strings.Builder
+		w.previous = 0
+
+		err := w.write(ctx, &limitedWriter{out: &title, max: w.maxBytes}, w.outline.Title(entry), entry.Command)
+		if err != nil {
+			return nil, err
+		}
+
+		copied := *entry
+		copied.Title = title.String()
+
+		copied.Children, err = w.renderEntries(ctx, entry.Children)
+		if err != nil {
+			return nil, err
+		}
+
+		rendered = append(rendered, &copied)
+	}
+
+	return rendered, nil
+}
+
+// WriteDocument renders the document wrapped in the document template of the
+// target. Without a document template, it is equal to Write.
+func (w *Writer) WriteDocument(ctx context.Context, out io.Writer, document parser.Element) error {
+	if w.document == nil {
+		return w.Write(ctx, out, document)
+	}
+
+	var body strings.Builder
+
+	err := w.Write(ctx, &body, document)
+	if err != nil {
+		return err
+	}
+
+	documentContext := &schema.DocumentContext{
+		Body:      body.String(),
+		Meta:      w.metadata,
+		Packages:  w.usedPackages,
+		Toc:       w.renderedToc,
+		Collected: w.buckets,
+	}
+
+	if w.references != nil && w.citations != nil {
+		var references strings.Builder
+
+		err = w.writeReferences(&limitedWriter{out: &references, max: w.maxBytes})
+		if err != nil {
+			return err
+		}
+
+		documentContext.Bibliography = references.String()
+	}
+
+	if w.outline != nil {
+		documentContext.Outline = w.entries
+	}
+
+	return w.document.Execute(&limitedWriter{out: out, max: w.maxBytes}, documentContext)
+}
+
+func (w *Writer) write(ctx context.Context, out *limitedWriter, element parser.Element, parent string) error {
 	switch v := element.(type) {
 	case *parser.TextContent:
-		return v.String()
+		text := w.text(v.TextContent, w.previous)
+		if last, size := utf8.DecodeLastRuneInString(v.TextContent); size > 0 {
+			w.previous = last
+		}
 
+		return withPosition(out.writeString(text), v.Position)
 	case *parser.Block:
-		result := ""
 		for _, e := range v.Content() {
-			result += Write(targetSchema, e)
+			err := w.write(ctx, out, e, parent)
+			if err != nil {
+				return err
+			}
 		}
-		return result
+		return nil
 	case *parser.Command:
-		var commandExpression *string
-		for _, c := range targetSchema.Commands {
-			if c.Command == v.Name {
-				commandExpression = &c.Expression
+		err := ctx.Err()
+		if err != nil {
+			return err
+		}
+
+		for _, p := range w.packages[v.Name] {
+			if !slices.Contains(w.usedPackages, p) {
+				w.usedPackages = append(w.usedPackages, p)
 			}
 		}
 
-		if commandExpression == nil {
-			panic("Command not found: " + v.Name)
+		if w.toc != nil && w.outline != nil && v.Name == w.outline.TocCommand {
+			return out.writeString(w.renderedToc)
+		}
+
+		if w.references != nil && w.citations != nil && v.Name == w.citations.Command {
+			return withPosition(w.writeReferences(out), v.Position)
 		}
 
-		result := *commandExpression
-		for i, a := range v.Arguments {
-			r := Write(targetSchema, a)
-			result = strings.ReplaceAll(result, fmt.Sprintf("$%d", i+1), r)
+		if tmpl, ok := w.templates[v.Name]; ok {
+			return withPosition(w.executeTemplate(ctx, out, tmpl, v, parent), v.Position)
 		}
 
-		return result
+		if bucket, ok := w.flush[v.Name]; ok {
+			items := w.buckets[bucket]
+			delete(w.buckets, bucket)
+			return out.writeString(strings.Join(items, ""))
+		}
+
+		expression, ok := w.expressions[v.Name]
+		if !ok {
+			return &parser.Error{Position: v.Position, Err: fmt.Errorf("%w: %s", ErrCommandNotFound, v.Name)}
+		}
+
+		number := ""
+		if w.numbering != nil {
+			value, _ := w.numbering.Of(v)
+			number = value.Number
+		}
+
+		err = expression.Execute(out, number, func(i int, filter string) error {
+			if i > len(v.Arguments) {
+				return &parser.Error{Position: v.Position, Err: fmt.Errorf("%w: command %s has no argument %d", ErrMissingArgument, v.Name, i)}
+			}
+
+			if filter != "" {
+				escaped, err := schema.EscapeArgument(filter, parser.PlainText(v.Arguments[i-1]))
+				if err != nil {
+					return err
+				}
+
+				return out.writeString(escaped)
+			}
+
+			return w.write(ctx, out, v.Arguments[i-1], v.Name)
+		})
+
+		return withPosition(err, v.Position)
 	default:
-		return element.String()
+		return out.writeString(element.String())
+	}
+}
+
+func (w *Writer) executeTemplate(ctx context.Context, out *limitedWriter, tmpl *schema.Template, command *parser.Command, parent string) error {
+	render := func(i int) (string, error) {
+		var argument strings.Builder
+
+		err := w.write(ctx, &limitedWriter{out: &argument, max: w.maxBytes}, command.Arguments[i-1], command.Name)
+		if err != nil {
+			return "", err
+		}
+
+		return argument.String(), nil
+	}
+
+	text := func(i int) string {
+		return parser.PlainText(command.Arguments[i-1])
 	}
+
+	commandContext := schema.NewCommandContext(command.Name, parent, w.metadata, len(command.Arguments), render, text)
+
+	if bucket, ok := w.flush[command.Name]; ok {
+		commandContext.Items = w.buckets[bucket]
+		delete(w.buckets, bucket)
+	}
+
+	if w.numbering != nil {
+		value, _ := w.numbering.Of(command)
+
+		counter := func(name string) int {
+			return w.numbering.Counter(command, name)
+		}
+
+		ref := func(label string) (string, error) {
+			value, err := w.numbering.Ref(label)
+			return value.Number, err
+		}
+
+		commandContext.SetNumbering(value.Number, counter, ref)
+	}
+
+	if w.outline != nil {
+		if entry, ok := w.outline.Entry(command); ok {
+			commandContext.Anchor = entry.Anchor
+		}
+	}
+
+	if w.citations != nil {
+		commandContext.Citations = w.citations.Of(command)
+	}
+
+	if c, ok := w.collect[command.Name]; ok {
+		err := w.push(c, commandContext)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tmpl.Execute(out, commandContext)
+}
+
+// push renders the item before the command itself, so the command template
+// can refer to its index. The slot of the item is reserved before rendering,
+// so items collected from its arguments follow it.
+func (w *Writer) push(c *collector, commandContext *schema.CommandContext) error {
+	w.indices[c.bucket] += 1
+	commandContext.Index = w.indices[c.bucket]
+
+	slot := len(w.buckets[c.bucket])
+	w.buckets[c.bucket] = append(w.buckets[c.bucket], "")
+
+	var item strings.Builder
+
+	err := c.template.Execute(&limitedWriter{out: &item, max: w.maxBytes}, commandContext)
+	if err != nil {
+		return err
+	}
+
+	// the bucket may have been flushed by an argument
+	if slot < len(w.buckets[c.bucket]) {
+		w.buckets[c.bucket][slot] = item.String()
+	}
+
+	return nil
+}
+
+// withPosition adds the position to errors that don't have one
+func withPosition(err error, position parser.Position) error {
+	var positionError *parser.Error
+	if err == nil || errors.As(err, &positionError) {
+		return err
+	}
+
+	return &parser.Error{Position: position, Err: err}
+}
+
+// limitedWriter counts written bytes and fails when the limit is exceeded
+type limitedWriter struct {
+	out     io.Writer
+	written int
+	max     int
+}
+
+func (lw *limitedWriter) Write(p []byte) (int, error) {
+	lw.written += len(p)
+	if lw.max > 0 && lw.written > lw.max {
+		return 0, &parser.LimitError{Limit: "output size", Max: lw.max}
+	}
+
+	return lw.out.Write(p)
+}
+
+func (lw *limitedWriter) writeString(s string) error {
+	_, err := io.WriteString(lw, s)
+	return err
 }
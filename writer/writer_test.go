@@ -0,0 +1,420 @@
+package writer_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ubavic/mint/outline"
+	"github.com/ubavic/mint/parser"
+	"github.com/ubavic/mint/schema"
+	"github.com/ubavic/mint/writer"
+)
+
+var target = schema.Target{
+	Name: "HTML",
+	Commands: []schema.TargetCommand{
+		{Command: "p", Expression: "<p>$1</p>"},
+		{Command: "b", Expression: "<b>$1</b>"},
+		{Command: "link", Expression: "<a href=\"$2\">$1</a>"},
+		{Command: "br", Expression: "<br>"},
+		{Command: "price", Expression: "$$$1"},
+		{Command: "list", Template: "<ul>{{range .Args}}<li>{{.}}</li>{{end}}</ul>"},
+		{Command: "h", Template: `<h2 id="{{slug (.Text 1)}}">{{.Arg 1}}</h2>`},
+		{Command: "note", Template: "{{if eq .Parent \"p\"}}<span>{{.Arg 1}}</span>{{else}}<aside>{{.Arg 1}}</aside>{{end}}"},
+	},
+}
+
+func parse(t *testing.T, input string) *parser.Block {
+	tokenizer := parser.NewTokenizer(bufio.NewReader(strings.NewReader(input)))
+	p := parser.NewParser(tokenizer.Tokenize(), &parser.OptimisticValidator{})
+
+	block, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%s\"", err)
+	}
+
+	return block
+}
+
+func TestWriter(t *testing.T) {
+	testCases := []struct {
+		input          string
+		expectedResult string
+		expectedError  error
+	}{
+		{input: "", expectedResult: ""},
+		{input: "text", expectedResult: "text"},
+		{input: "@p{a @b{b}}", expectedResult: "<p>a <b>b</b></p>"},
+		{input: "@link{x}{y}", expectedResult: "<a href=\"y\">x</a>"},
+		{input: "@p{a@br b}", expectedResult: "<p>a<br> b</p>"},
+		{input: "@link{$2}{y}", expectedResult: "<a href=\"y\">$2</a>"},
+		{input: "@price{5}", expectedResult: "$5"},
+		{input: "@list{a}{@b{b}}{c}", expectedResult: "<ul><li>a</li><li><b>b</b></li><li>c</li></ul>"},
+		{input: "@h{Hello @b{World}}", expectedResult: "<h2 id=\"hello-world\">Hello <b>World</b></h2>"},
+		{input: "@note{a}@p{@note{b}}", expectedResult: "<aside>a</aside><p><span>b</span></p>"},
+		{input: "@list{@i{x}}", expectedError: writer.ErrCommandNotFound},
+		{input: "@p{a @i{b}}", expectedError: writer.ErrCommandNotFound},
+		{input: "@link{x}", expectedError: writer.ErrMissingArgument},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(
+			fmt.Sprintf("TestWriter%d", i),
+			func(t *testing.T) {
+				w, err := writer.New(&target)
+				if err != nil {
+					t.Fatalf("Expected no error, got \"%v\"", err)
+				}
+
+				var result strings.Builder
+
+				err = w.Write(context.Background(), &result, parse(t, testCase.input))
+				if !errors.Is(err, testCase.expectedError) {
+					t.Fatalf("Expected error \"%v\", got \"%v\"", testCase.expectedError, err)
+				}
+
+				if testCase.expectedError == nil && result.String() != testCase.expectedResult {
+					t.Errorf("Expected \"%s\", got \"%s\"", testCase.expectedResult, result.String())
+				}
+			},
+		)
+	}
+}
+
+func TestWriterEscaping(t *testing.T) {
+	target := schema.Target{
+		Escape:     "html",
+		Typography: []string{"quotes"},
+		Commands: []schema.TargetCommand{
+			{Command: "p", Expression: "<p>$1</p>"},
+			{Command: "link", Expression: "<a href=\"${2|url}\">$1</a>"},
+			{Command: "code", Template: "<code>{{escape \"html\" (.Text 1)}}</code>"},
+		},
+	}
+
+	w, err := writer.New(&target)
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	var result strings.Builder
+
+	err = w.Write(context.Background(), &result, parse(t, "@p{\"A\" & <B>} @link{x < y}{https://example.com/?q=a b&c=\"d\"} @code{a\"b}"))
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	expected := "<p>“A” &amp; &lt;B&gt;</p><a href=\"https://example.com/?q=a%20b&c=%22d%22\">x &lt; y</a><code>a&quot;b</code>"
+	if result.String() != expected {
+		t.Errorf("Expected \"%s\", got \"%s\"", expected, result.String())
+	}
+}
+
+func TestWriterQuotesAroundCommands(t *testing.T) {
+	target := schema.Target{
+		Typography: []string{"quotes"},
+		Commands: []schema.TargetCommand{
+			{Command: "b", Expression: "<b>$1</b>"},
+			{Command: "br", Expression: "<br>"},
+		},
+	}
+
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{input: "\"@b{x}\"", expected: "“<b>x</b>”"},
+		{input: "@b{Mint}'s", expected: "<b>Mint</b>’s"},
+		{input: "say @b{\"hi\"}", expected: "say <b>“hi”</b>"},
+		{input: "a@br\n\"b\"", expected: "a<br>\n“b”"},
+		{input: "(@b{\"x\"})", expected: "(<b>“x”</b>)"},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(fmt.Sprintf("TestWriterQuotesAroundCommands%d", i), func(t *testing.T) {
+			w, err := writer.New(&target)
+			if err != nil {
+				t.Fatalf("Expected no error, got \"%v\"", err)
+			}
+
+			var result strings.Builder
+
+			err = w.Write(context.Background(), &result, parse(t, testCase.input))
+			if err != nil {
+				t.Fatalf("Expected no error, got \"%v\"", err)
+			}
+
+			if result.String() != testCase.expected {
+				t.Errorf("Expected \"%s\", got \"%s\"", testCase.expected, result.String())
+			}
+		})
+	}
+}
+
+func TestWriterDocument(t *testing.T) {
+	target := schema.Target{
+		Document: "{{range .Packages}}\\usepackage{ {{- . -}} }\n{{end}}\\title{ {{- .Meta.title -}} }\n{{.Body}}\n",
+		Commands: []schema.TargetCommand{
+			{Command: "p", Expression: "$1\n"},
+			{Command: "link", Expression: "\\href{$2}{$1}", Packages: []string{"hyperref"}},
+			{Command: "url", Expression: "\\url{$1}", Packages: []string{"url", "hyperref"}},
+		},
+	}
+
+	w, err := writer.New(&target)
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+	w.SetMetadata(map[string]string{"title": "Mint"})
+
+	var result strings.Builder
+
+	err = w.WriteDocument(context.Background(), &result, parse(t, "@p{@link{a}{b}}@p{@url{c}}"))
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	expected := "\\usepackage{hyperref}\n\\usepackage{url}\n\\title{Mint}\n\\href{b}{a}\n\\url{c}\n\n"
+	if result.String() != expected {
+		t.Errorf("Expected \"%s\", got \"%s\"", expected, result.String())
+	}
+}
+
+func TestWriterToc(t *testing.T) {
+	s := &schema.Schema{
+		Source: schema.Source{
+			TocCommand: "toc",
+			Commands: []schema.Command{
+				{Command: "toc"},
+				{Command: "h1", Arguments: 1, Heading: &schema.Heading{Level: 1, Title: 1}},
+				{Command: "h2", Arguments: 1, Heading: &schema.Heading{Level: 2, Title: 1}},
+				{Command: "b", Arguments: 1},
+			},
+		},
+	}
+
+	target := schema.Target{
+		Toc: `{{define "entries"}}<ul>{{range .}}<li><a href="#{{.Anchor}}">{{.Title}}</a>{{if .Children}}{{template "entries" .Children}}{{end}}</li>{{end}}</ul>{{end}}<nav>{{template "entries" .Entries}}</nav>`,
+		Commands: []schema.TargetCommand{
+			{Command: "toc", Expression: ""},
+			{Command: "h1", Template: `<h1 id="{{.Anchor}}">{{.Arg 1}}</h1>`},
+			{Command: "h2", Template: `<h2 id="{{.Anchor}}">{{.Arg 1}}</h2>`},
+			{Command: "b", Expression: "<b>$1</b>"},
+		},
+	}
+
+	w, err := writer.New(&target)
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	document := parse(t, "@toc\n@h1{One}@h2{A @b{B}}@h1{Two}")
+	w.SetOutline(outline.Collect(s, document, nil))
+
+	var result strings.Builder
+
+	err = w.Write(context.Background(), &result, document)
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	expected := `<nav><ul><li><a href="#one">One</a><ul><li><a href="#a-b">A <b>B</b></a></li></ul></li><li><a href="#two">Two</a></li></ul></nav><h1 id="one">One</h1><h2 id="a-b">A <b>B</b></h2><h1 id="two">Two</h1>`
+	if result.String() != expected {
+		t.Errorf("Expected \"%s\", got \"%s\"", expected, result.String())
+	}
+}
+
+func TestWriterSharedOutline(t *testing.T) {
+	s := &schema.Schema{
+		Source: schema.Source{
+			TocCommand: "toc",
+			Commands: []schema.Command{
+				{Command: "toc"},
+				{Command: "h", Arguments: 1, Heading: &schema.Heading{Level: 1, Title: 1}},
+				{Command: "b", Arguments: 1},
+			},
+		},
+	}
+
+	targets := []schema.Target{
+		{
+			Toc: "{{range .Entries}}[{{.Title}}]{{end}}",
+			Commands: []schema.TargetCommand{
+				{Command: "toc", Expression: ""},
+				{Command: "h", Expression: "<h1>$1</h1>"},
+				{Command: "b", Expression: "<b>$1</b>"},
+			},
+		},
+		{
+			Toc: "{{range .Entries}}[{{.Title}}]{{end}}",
+			Commands: []schema.TargetCommand{
+				{Command: "toc", Expression: ""},
+				{Command: "h", Expression: "# $1\n"},
+				{Command: "b", Expression: "**$1**"},
+			},
+		},
+	}
+
+	document := parse(t, "@toc@h{A @b{B}}")
+	o := outline.Collect(s, document, nil)
+
+	writers := []*writer.Writer{}
+	for _, target := range targets {
+		w, err := writer.New(&target)
+		if err != nil {
+			t.Fatalf("Expected no error, got \"%v\"", err)
+		}
+
+		w.SetOutline(o)
+		writers = append(writers, w)
+	}
+
+	expected := []string{"[A <b>B</b>]<h1>A <b>B</b></h1>", "[A **B**]# A **B**\n"}
+
+	for i, w := range writers {
+		var result strings.Builder
+
+		err := w.Write(context.Background(), &result, document)
+		if err != nil {
+			t.Fatalf("Expected no error, got \"%v\"", err)
+		}
+
+		if result.String() != expected[i] {
+			t.Errorf("Expected \"%s\", got \"%s\"", expected[i], result.String())
+		}
+	}
+
+	if o.Entries[0].Title != "" {
+		t.Errorf("Expected the shared outline without rendered titles, got \"%s\"", o.Entries[0].Title)
+	}
+}
+
+func TestWriterCollect(t *testing.T) {
+	target := schema.Target{
+		Document: `{{.Body}}{{with .Collected.notes}}<ol>{{range .}}{{.}}{{end}}</ol>{{end}}`,
+		Commands: []schema.TargetCommand{
+			{Command: "p", Expression: "<p>$1</p>"},
+			{
+				Command:  "footnote",
+				Template: `<sup><a href="#fn{{.Index}}">{{.Index}}</a></sup>`,
+				Collect: &schema.Collect{
+					Bucket:   "notes",
+					Template: `<li id="fn{{.Index}}">{{.Arg 1}}</li>`,
+				},
+			},
+			{Command: "notes", Flush: "notes", Template: `{{if .Items}}<ol>{{range .Items}}{{.}}{{end}}</ol>{{end}}`},
+		},
+	}
+
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{
+			input:    "@p{a@footnote{x}}@notes@notes",
+			expected: `<p>a<sup><a href="#fn1">1</a></sup></p><ol><li id="fn1">x</li></ol>`,
+		},
+		{
+			input:    "@p{a@footnote{x}b@footnote{y@footnote{z}}}@notes@p{c@footnote{w}}",
+			expected: `<p>a<sup><a href="#fn1">1</a></sup>b<sup><a href="#fn2">2</a></sup></p><ol><li id="fn1">x</li><li id="fn2">y<sup><a href="#fn3">3</a></sup></li><li id="fn3">z</li></ol><p>c<sup><a href="#fn4">4</a></sup></p><ol><li id="fn4">w</li></ol>`,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TestWriterCollect%d", i), func(t *testing.T) {
+			w, err := writer.New(&target)
+			if err != nil {
+				t.Fatalf("Expected no error, got \"%v\"", err)
+			}
+
+			var result strings.Builder
+
+			err = w.WriteDocument(context.Background(), &result, parse(t, tc.input))
+			if err != nil {
+				t.Fatalf("Expected no error, got \"%v\"", err)
+			}
+
+			if result.String() != tc.expected {
+				t.Errorf("Expected \"%s\", got \"%s\"", tc.expected, result.String())
+			}
+		})
+	}
+}
+
+func TestWriterCollectInHeading(t *testing.T) {
+	s := &schema.Schema{
+		Source: schema.Source{
+			Commands: []schema.Command{
+				{Command: "h", Arguments: 1, Heading: &schema.Heading{Level: 1, Title: 1}},
+				{Command: "fn", Arguments: 1},
+			},
+		},
+	}
+
+	target := schema.Target{
+		Document: `{{.Body}}{{with .Collected.notes}}<ol>{{range .}}{{.}}{{end}}</ol>{{end}}`,
+		Commands: []schema.TargetCommand{
+			{Command: "h", Template: `<h1 id="{{.Anchor}}">{{.Arg 1}}</h1>`},
+			{
+				Command:  "fn",
+				Template: `<sup>{{.Index}}</sup>`,
+				Collect:  &schema.Collect{Bucket: "notes", Template: `<li>{{.Arg 1}}</li>`},
+			},
+		},
+	}
+
+	w, err := writer.New(&target)
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	document := parse(t, "@h{Title@fn{note}}")
+	w.SetOutline(outline.Collect(s, document, nil))
+
+	var result strings.Builder
+
+	err = w.WriteDocument(context.Background(), &result, document)
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	expected := `<h1 id="titlenote">Title<sup>1</sup></h1><ol><li>note</li></ol>`
+	if result.String() != expected {
+		t.Errorf("Expected \"%s\", got \"%s\"", expected, result.String())
+	}
+}
+
+func TestWriterCommandNotFoundPosition(t *testing.T) {
+	w, err := writer.New(&target)
+	if err != nil {
+		t.Fatalf("Expected no error, got \"%v\"", err)
+	}
+
+	err = w.Write(context.Background(), &strings.Builder{}, parse(t, "@p{a}\n@p{b @i{c}}"))
+
+	var positionError *parser.Error
+	if !errors.As(err, &positionError) {
+		t.Fatalf("Expected positioned error, got \"%v\"", err)
+	}
+
+	if positionError.Position != (parser.Position{Line: 2, Column: 6}) {
+		t.Errorf("Expected error at 2:6, got %v", positionError.Position)
+	}
+}
+
+func TestWriterDuplicateCommand(t *testing.T) {
+	_, err := writer.New(&schema.Target{
+		Commands: []schema.TargetCommand{
+			{Command: "p", Expression: "$1"},
+			{Command: "p", Expression: "$1"},
+		},
+	})
+
+	if !errors.Is(err, writer.ErrDuplicateCommand) {
+		t.Errorf("Expected error \"%v\", got \"%v\"", writer.ErrDuplicateCommand, err)
+	}
+}